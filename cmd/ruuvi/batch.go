@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marcgeld/ruuvi/common"
+	"github.com/marcgeld/ruuvi/tag"
+)
+
+// maxLineLength is the longest batch input line accepted, in bytes.
+const maxLineLength = 1 << 20
+
+// handleDecodeBatch decodes the newline-delimited payloads of opts.File and
+// writes one record per line to stdout in the format of opts.Output. Bad lines are reported on
+// stderr and skipped.
+func handleDecodeBatch(opts decodeOptions) error {
+	in := os.Stdin
+	if opts.File != "-" {
+		f, err := os.Open(opts.File)
+		if err != nil {
+			return fmt.Errorf("failed to open input: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		in = f
+	}
+
+	return decodeStream(in, os.Stdout, os.Stderr, opts)
+}
+
+// decodeStream decodes the lines of r into records written to w,
+// reporting lines that fail to decode on errw. Blank lines and lines starting
+// with '#' are ignored. Returns an error if any line failed.
+func decodeStream(r io.Reader, w, errw io.Writer, opts decodeOptions) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+
+	out := bufio.NewWriter(w)
+	defer func() { _ = out.Flush() }()
+	rw, err := newRecordWriter(out, opts)
+	if err != nil {
+		return err
+	}
+
+	// Records are passed on as they are decoded when reading a live stream
+	live := opts.File == "-"
+
+	var lines, failed int
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines++
+
+		rec, err := decodeBatchLine(line, opts)
+		if err != nil {
+			failed++
+			fmt.Fprintf(errw, "line %d: %v\n", lineNo, err)
+			continue
+		}
+		rec.Line = lineNo
+
+		if err := rw.WriteRecord(rec); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		if live {
+			if err := flushAll(rw, out); err != nil {
+				return fmt.Errorf("failed to write output: %w", err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	if err := flushAll(rw, out); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d lines failed to decode", failed, lines)
+	}
+	return nil
+}
+
+// decodeBatchLine parses and decodes a single line of batch input into a
+// record.
+func decodeBatchLine(line string, opts decodeOptions) (*record, error) {
+	env, err := decodeEnvelope(line, opts.InputEncoding)
+	if err != nil {
+		return nil, err
+	}
+	return newRecord(env, opts)
+}
+
+// decodeEnvelope parses and decodes a single line of batch input.
+func decodeEnvelope(line, inputEncoding string) (*tag.Envelope, error) {
+	env, payload, err := parseBatchLine(line)
+	if err != nil {
+		return nil, err
+	}
+
+	env.Raw, err = parsePayload(payload, inputEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	env.Data, err = tag.Decode(env.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode data: %w", err)
+	}
+
+	return &env, nil
+}
+
+// parseBatchLine splits a line of batch input into its columns. Columns are
+// separated by commas, tabs or spaces. The payload is the last column; it may
+// be preceded by a timestamp (RFC 3339, or Unix time in seconds or
+// milliseconds), a MAC address and an RSSI in dBm, in any order. These are
+// returned in an envelope without data. In space-separated lines, a trailing
+// run of single hex bytes such as "05 12 FC" is taken as one payload column.
+func parseBatchLine(line string) (env tag.Envelope, payload string, err error) {
+	var columns []string
+	switch {
+	case strings.Contains(line, ","):
+		columns = strings.Split(line, ",")
+	case strings.Contains(line, "\t"):
+		columns = strings.Split(line, "\t")
+	default:
+		columns = joinPayloadBytes(strings.Fields(line))
+	}
+	for i := range columns {
+		columns[i] = strings.TrimSpace(columns[i])
+	}
+
+	for _, col := range columns[:len(columns)-1] {
+		if ts, ok := parseTimestamp(col); ok && env.ReceivedAt.IsZero() {
+			env.ReceivedAt = ts
+			continue
+		}
+
+		if strings.ContainsAny(col, ":-") && env.Address == nil {
+			if mac, err := common.ParseMACAddress(col); err == nil {
+				env.Address = &mac
+				continue
+			}
+		}
+
+		if rssi, err := strconv.Atoi(col); err == nil && rssi >= -127 && rssi <= 20 && env.RSSI == nil {
+			env.RSSI = &rssi
+			continue
+		}
+
+		return tag.Envelope{}, "", fmt.Errorf("unrecognized column %q", col)
+	}
+
+	return env, columns[len(columns)-1], nil
+}
+
+// hexByteToken matches a single hex byte of a space-separated payload.
+var hexByteToken = regexp.MustCompile(`^(0[xX])?[0-9A-Fa-f]{2}$`)
+
+// joinPayloadBytes joins the trailing run of hex byte tokens of a
+// space-separated line into one column. A single byte is left alone, as it
+// is more likely an RSSI or a mistyped column than a payload.
+func joinPayloadBytes(columns []string) []string {
+	start := len(columns)
+	for start > 0 && hexByteToken.MatchString(columns[start-1]) {
+		start--
+	}
+	if len(columns)-start < 2 {
+		return columns
+	}
+	return append(columns[:start], strings.Join(columns[start:], " "))
+}
+
+// parseTimestamp parses an RFC 3339 timestamp or a Unix time in seconds or
+// milliseconds.
+func parseTimestamp(s string) (time.Time, bool) {
+	if ts, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return ts, true
+	}
+
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		switch {
+		case n >= 1e12:
+			return time.UnixMilli(n).UTC(), true
+		case n >= 1e9:
+			return time.Unix(n, 0).UTC(), true
+		default:
+			return time.Time{}, false
+		}
+	}
+
+	// Fractional Unix seconds
+	unix, err := strconv.ParseFloat(s, 64)
+	if err != nil || unix < 1e9 || unix >= 1e12 {
+		return time.Time{}, false
+	}
+	sec, frac := math.Modf(unix)
+	return time.Unix(int64(sec), int64(math.Round(frac*1e6))*1e3).UTC(), true
+}
+
+// flushAll flushes the record writer and then the buffered output.
+func flushAll(rw recordWriter, out *bufio.Writer) error {
+	if err := rw.Flush(); err != nil {
+		return err
+	}
+	return out.Flush()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/marcgeld/ruuvi/tag"
+)
+
+func handleExplain(payload, inputEncoding string) error {
+	if payload == "" {
+		return fmt.Errorf("--hex flag is required")
+	}
+
+	data, err := parsePayload(payload, inputEncoding)
+	if err != nil {
+		return err
+	}
+
+	e, err := tag.Explain(data)
+	if err != nil {
+		return fmt.Errorf("failed to explain data: %w", err)
+	}
+
+	return writeExplanation(os.Stdout, e, len(data))
+}
+
+// writeExplanation prints an explanation as an annotated hexdump, one row
+// per field.
+func writeExplanation(w io.Writer, e *tag.Explanation, size int) error {
+	if _, err := fmt.Fprintf(w, "Data Format %s, %d bytes\n\n", e.Format, size); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "OFFSET\tBYTES\tFIELD\tRAW\tSCALING\tVALUE\tNOTE")
+
+	decrypted := false
+	for _, f := range e.Fields {
+		if f.Decrypted && !decrypted {
+			// Offsets of the decrypted block start over at 0
+			fmt.Fprintln(tw, "\t\t\t\t\t\t")
+			fmt.Fprintln(tw, "Decrypted block:\t\t\t\t\t\t")
+			decrypted = true
+		}
+
+		fmt.Fprintf(tw, "%s\t% X\t%s\t%s\t%s\t%s\t%s\n",
+			explainOffset(f), f.Bytes, f.Name, explainRaw(f), explainScaling(f), explainValue(f), f.Note)
+	}
+
+	return tw.Flush()
+}
+
+func explainOffset(f tag.ExplainedField) string {
+	if len(f.Bytes) == 1 {
+		return strconv.Itoa(f.Offset)
+	}
+	return fmt.Sprintf("%d-%d", f.Offset, f.Offset+len(f.Bytes)-1)
+}
+
+// explainRaw formats the raw value of a field, blank for MAC addresses and
+// blocks of bytes that are not decoded.
+func explainRaw(f tag.ExplainedField) string {
+	if len(f.Bytes) > 1 && f.Raw == 0 && f.Scale == 0 {
+		return ""
+	}
+	return strconv.FormatInt(f.Raw, 10)
+}
+
+// explainScaling formats the linear scaling of a field, e.g. "× 0.005" or
+// "× 2 - 40". Identity scaling is left blank.
+func explainScaling(f tag.ExplainedField) string {
+	var parts []string
+	if f.Scale != 0 && f.Scale != 1 {
+		parts = append(parts, "× "+strconv.FormatFloat(f.Scale, 'g', -1, 64))
+	}
+	switch {
+	case f.ValueOffset > 0:
+		parts = append(parts, "+ "+strconv.FormatFloat(f.ValueOffset, 'g', -1, 64))
+	case f.ValueOffset < 0:
+		parts = append(parts, "- "+strconv.FormatFloat(-f.ValueOffset, 'g', -1, 64))
+	}
+	return strings.Join(parts, " ")
+}
+
+func explainValue(f tag.ExplainedField) string {
+	if f.NotAvailable {
+		return "n/a"
+	}
+
+	var s string
+	switch v := f.Value.(type) {
+	case nil:
+		return ""
+	case float64:
+		s = strconv.FormatFloat(math.Round(v*1e4)/1e4, 'f', -1, 64)
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if f.Unit != "" {
+		s += " " + f.Unit
+	}
+	return s
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/marcgeld/ruuvi/tag"
+)
+
+// inputEncodings lists the values accepted by the --input-encoding flag of decode.
+var inputEncodings = []string{"auto", "hex", "base64"}
+
+// parsePayload converts a payload as pasted from other tools into raw bytes.
+//
+// Hex may carry a 0x prefix on the whole string or on every byte and may be
+// separated by spaces, colons or dashes, e.g. "0x05 12 FC" or "05:12:FC".
+// Base64 is standard or URL-safe with padding, as sent by the Ruuvi Gateway.
+// With encoding "auto", input that is valid hex is taken as hex and anything
+// else as base64. A leading Ruuvi company ID (bytes 99 04) is stripped so that
+// full manufacturer specific data can be pasted as well.
+func parsePayload(s, encoding string) ([]byte, error) {
+	var data []byte
+	var err error
+
+	switch encoding {
+	case "hex":
+		data, err = parseHex(s)
+	case "base64":
+		data, err = parseBase64(s)
+	case "", "auto":
+		data, err = parseHex(s)
+		if err != nil {
+			if b, b64err := parseBase64(s); b64err == nil {
+				data, err = b, nil
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown input encoding %q (supported: %s)", encoding, strings.Join(inputEncodings, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > 2 && binary.LittleEndian.Uint16(data) == tag.RuuviCompanyID {
+		data = data[2:]
+	}
+
+	return data, nil
+}
+
+// parseHex decodes hex with optional 0x prefixes and separators.
+func parseHex(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if strings.ContainsAny(s, " \t:-") {
+		// Separated bytes, each possibly with its own 0x prefix
+		parts := strings.FieldsFunc(s, func(r rune) bool {
+			return r == ' ' || r == '\t' || r == ':' || r == '-'
+		})
+		for i, p := range parts {
+			parts[i] = trimHexPrefix(p)
+		}
+		s = strings.Join(parts, "")
+	} else {
+		s = trimHexPrefix(s)
+	}
+
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex string: %w", err)
+	}
+	return data, nil
+}
+
+func trimHexPrefix(s string) string {
+	if len(s) >= 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		return s[2:]
+	}
+	return s
+}
+
+// parseBase64 decodes padded standard or URL-safe base64.
+func parseBase64(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		if b, urlErr := base64.URLEncoding.DecodeString(s); urlErr == nil {
+			return b, nil
+		}
+		return nil, fmt.Errorf("invalid base64 string: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParsePayload(t *testing.T) {
+	const want = "0512fc5394c37c0004fffc040cac364200cdcbb8334c884f"
+
+	tests := []struct {
+		name     string
+		input    string
+		encoding string
+		wantErr  string
+	}{
+		{name: "bare hex", input: "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F"},
+		{name: "lowercase hex with 0x prefix", input: "0x" + want},
+		{name: "0x prefix and spaces", input: "0x05 12 FC 53 94 C3 7C 00 04 FF FC 04 0C AC 36 42 00 CD CB B8 33 4C 88 4F"},
+		{name: "0x prefix on every byte", input: "0x05 0x12 0xFC 0x53 0x94 0xC3 0x7C 0x00 0x04 0xFF 0xFC 0x04 0x0C 0xAC 0x36 0x42 0x00 0xCD 0xCB 0xB8 0x33 0x4C 0x88 0x4F"},
+		{name: "colon separated", input: "05:12:FC:53:94:C3:7C:00:04:FF:FC:04:0C:AC:36:42:00:CD:CB:B8:33:4C:88:4F"},
+		{name: "dash separated", input: "05-12-FC-53-94-C3-7C-00-04-FF-FC-04-0C-AC-36-42-00-CD-CB-B8-33-4C-88-4F"},
+		{name: "company ID prefix", input: "99040512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F"},
+		{name: "surrounding whitespace", input: "  0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F\t"},
+		{name: "base64", input: "BRL8U5TDfAAE//wEDKw2QgDNy7gzTIhP"},
+		{name: "url-safe base64 with company ID", input: "mQQFEvxTlMN8AAT__AQMrDZCAM3LuDNMiE8="},
+		{name: "forced base64", input: "BRL8U5TDfAAE//wEDKw2QgDNy7gzTIhP", encoding: "base64"},
+		{name: "forced hex", input: "05:12:FC:53:94:C3:7C:00:04:FF:FC:04:0C:AC:36:42:00:CD:CB:B8:33:4C:88:4F", encoding: "hex"},
+		{name: "base64 with forced hex", input: "BRL8U5TDfAAE//wEDKw2QgDNy7gzTIhP", encoding: "hex", wantErr: "invalid hex string"},
+		{name: "hex with forced base64", input: "0x05 12 FC", encoding: "base64", wantErr: "invalid base64 string"},
+		{name: "not hex", input: "nothex", wantErr: "invalid hex string"},
+		{name: "odd length hex", input: "0512F", wantErr: "invalid hex string"},
+		{name: "unknown encoding", input: "05", encoding: "binary", wantErr: "unknown input encoding"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePayload(tt.input, tt.encoding)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got: %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if hex.EncodeToString(got) != want {
+				t.Errorf("got %x, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestRun_Decode_InputEncoding(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{"ruuvi", "decode", "--input-encoding", "base64", "--hex", "BRL8U5TDfAAE//wEDKw2QgDNy7gzTIhP"}
+	out, _ := captureStdoutStderr(func() {
+		if err := run(); err != nil {
+			t.Fatalf("run() returned error: %v", err)
+		}
+	})
+	if !strings.Contains(out, `"format": 5`) {
+		t.Errorf("expected Format 5 output, got: %s", out)
+	}
+
+	os.Args = []string{"ruuvi", "decode", "--input-encoding", "binary", "--hex", "0512"}
+	_, _ = captureStdoutStderr(func() {
+		if err := run(); err == nil || !strings.Contains(err.Error(), `unknown input encoding "binary"`) {
+			t.Errorf("expected unknown input encoding error, got: %v", err)
+		}
+	})
+}
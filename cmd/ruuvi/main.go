@@ -7,7 +7,13 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/marcgeld/ruuvi/common"
+	"github.com/marcgeld/ruuvi/derive"
 	"github.com/marcgeld/ruuvi/tag"
 )
 
@@ -28,12 +34,29 @@ func run() error {
 	// Define subcommands
 	decodeCmd := flag.NewFlagSet("decode", flag.ExitOnError)
 	encodeCmd := flag.NewFlagSet("encode", flag.ExitOnError)
+	explainCmd := flag.NewFlagSet("explain", flag.ExitOnError)
+	serveMetricsCmd := flag.NewFlagSet("serve-metrics", flag.ExitOnError)
 
 	// Decode flags
-	decodeHex := decodeCmd.String("hex", "", "Hex-encoded RuuviTag data to decode (required)")
+	decodeHex := decodeCmd.String("hex", "", "RuuviTag payload to decode as hex or base64 (required)")
+	decodeDerived := decodeCmd.Bool("derived", false, "Add derived metrics (dew point, air density, ...) to the output")
+	decodeFile := decodeCmd.String("file", "", "Decode newline-delimited payloads from a file, \"-\" for stdin")
+	decodeInputEncoding := decodeCmd.String("input-encoding", "auto", "Payload encoding: auto, hex or base64")
+	decodeOutput := decodeCmd.String("output", "", "Output format: json, ndjson, yaml, csv, table or influx (default json, ndjson with --file)")
+	decodeUnits := decodeCmd.String("units", "metric", "Units of temperatures, pressure and altitude: metric or imperial")
 
 	// Encode flags
-	encodeJSON := encodeCmd.String("json", "", "JSON-encoded Format5Data to encode (required)")
+	encodeJSON := encodeCmd.String("json", "", "JSON-encoded decoded data or Format5Data to encode (required)")
+
+	// Explain flags
+	explainHex := explainCmd.String("hex", "", "RuuviTag payload to explain as hex or base64 (required)")
+	explainInputEncoding := explainCmd.String("input-encoding", "auto", "Payload encoding: auto, hex or base64")
+
+	// Serve-metrics flags
+	serveListen := serveMetricsCmd.String("listen", ":9521", "Address to serve /metrics on")
+	serveFile := serveMetricsCmd.String("file", "-", "Newline-delimited payloads to read, \"-\" for stdin")
+	serveInputEncoding := serveMetricsCmd.String("input-encoding", "auto", "Payload encoding: auto, hex or base64")
+	serveStaleTimeout := serveMetricsCmd.Duration("stale-timeout", 5*time.Minute, "Drop tags not heard from for this long, 0 to keep them")
 
 	// Check if a subcommand was provided
 	if len(os.Args) < 2 {
@@ -47,7 +70,17 @@ func run() error {
 		if err := decodeCmd.Parse(os.Args[2:]); err != nil {
 			return err
 		}
-		return handleDecode(*decodeHex)
+		units, err := common.ParseUnitSystem(*decodeUnits)
+		if err != nil {
+			return err
+		}
+		return handleDecode(*decodeHex, decodeOptions{
+			Derived:       *decodeDerived,
+			File:          *decodeFile,
+			InputEncoding: *decodeInputEncoding,
+			Output:        *decodeOutput,
+			Units:         units,
+		})
 
 	case "encode":
 		if err := encodeCmd.Parse(os.Args[2:]); err != nil {
@@ -55,6 +88,29 @@ func run() error {
 		}
 		return handleEncode(*encodeJSON)
 
+	case "explain":
+		if err := explainCmd.Parse(os.Args[2:]); err != nil {
+			return err
+		}
+		return handleExplain(*explainHex, *explainInputEncoding)
+
+	case "schema":
+		return handleSchema()
+
+	case "serve-metrics":
+		if err := serveMetricsCmd.Parse(os.Args[2:]); err != nil {
+			return err
+		}
+		if !slices.Contains(inputEncodings, *serveInputEncoding) {
+			return fmt.Errorf("unknown input encoding %q (supported: %s)", *serveInputEncoding, strings.Join(inputEncodings, ", "))
+		}
+		return handleServeMetrics(serveMetricsOptions{
+			Listen:        *serveListen,
+			File:          *serveFile,
+			InputEncoding: *serveInputEncoding,
+			StaleTimeout:  *serveStaleTimeout,
+		})
+
 	default:
 		printUsage()
 		return fmt.Errorf("unknown command: %s", os.Args[1])
@@ -66,24 +122,135 @@ func printUsage() {
 	fmt.Fprintln(os.Stderr, "")
 	fmt.Fprintln(os.Stderr, "Commands:")
 	fmt.Fprintln(os.Stderr, "  decode    Decode RuuviTag data from hex to JSON")
-	fmt.Fprintln(os.Stderr, "  encode    Encode Format 5 data from JSON to hex")
+	fmt.Fprintln(os.Stderr, "  encode    Encode data from JSON to hex")
+	fmt.Fprintln(os.Stderr, "  explain   Print a payload byte by byte with field names, raw and scaled values")
+	fmt.Fprintln(os.Stderr, "  schema    Print the JSON Schema of decoded data")
+	fmt.Fprintln(os.Stderr, "  serve-metrics")
+	fmt.Fprintln(os.Stderr, "            Serve the latest reading of each tag as Prometheus metrics")
 	fmt.Fprintln(os.Stderr, "")
 	fmt.Fprintln(os.Stderr, "Decode flags:")
-	fmt.Fprintln(os.Stderr, "  --hex string    Hex-encoded RuuviTag data (required)")
+	fmt.Fprintln(os.Stderr, "  --hex string    RuuviTag payload (required unless --file is given): hex, optionally")
+	fmt.Fprintln(os.Stderr, "                  with 0x prefixes and space, colon or dash separators, or base64;")
+	fmt.Fprintln(os.Stderr, "                  a leading company ID 9904 is stripped")
+	fmt.Fprintln(os.Stderr, "  --file string   Newline-delimited payloads to decode, \"-\" for stdin;")
+	fmt.Fprintln(os.Stderr, "                  lines may start with timestamp, MAC and RSSI columns")
+	fmt.Fprintln(os.Stderr, "  --input-encoding string")
+	fmt.Fprintln(os.Stderr, "                  Payload encoding: auto (default), hex or base64")
+	fmt.Fprintln(os.Stderr, "  --output string Output format: json, ndjson, yaml, csv, table or influx")
+	fmt.Fprintln(os.Stderr, "                  (InfluxDB line protocol; default json, ndjson with --file)")
+	fmt.Fprintln(os.Stderr, "  --derived       Add derived metrics (dew point, air density, ...)")
+	fmt.Fprintln(os.Stderr, "  --units string  Units of temperatures, pressure and altitude: metric (default)")
+	fmt.Fprintln(os.Stderr, "                  or imperial (°F, inHg, ft; table and csv output only)")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintf(os.Stderr, "Supported formats: %s\n", supportedFormats())
 	fmt.Fprintln(os.Stderr, "")
 	fmt.Fprintln(os.Stderr, "Encode flags:")
-	fmt.Fprintln(os.Stderr, "  --json string   JSON-encoded Format5Data (required)")
+	fmt.Fprintln(os.Stderr, "  --json string   JSON as printed by decode, or Format5Data fields (required)")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Explain flags:")
+	fmt.Fprintln(os.Stderr, "  --hex string    RuuviTag payload, as for decode (required)")
+	fmt.Fprintln(os.Stderr, "  --input-encoding string")
+	fmt.Fprintln(os.Stderr, "                  Payload encoding: auto (default), hex or base64")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Serve-metrics flags:")
+	fmt.Fprintln(os.Stderr, "  --listen string Address to serve /metrics on (default :9521)")
+	fmt.Fprintln(os.Stderr, "  --file string   Payloads to read as for decode --file, \"-\" for stdin (default)")
+	fmt.Fprintln(os.Stderr, "  --input-encoding string")
+	fmt.Fprintln(os.Stderr, "                  Payload encoding: auto (default), hex or base64")
+	fmt.Fprintln(os.Stderr, "  --stale-timeout duration")
+	fmt.Fprintln(os.Stderr, "                  Drop tags not heard from for this long, 0 to keep them (default 5m)")
 }
 
-func handleDecode(hexStr string) error {
+// supportedFormats lists the formats registered with the tag package,
+// including any third-party formats, e.g. "2, 3, 4, 5, C5".
+func supportedFormats() string {
+	formats := tag.RegisteredFormats()
+	names := make([]string, len(formats))
+	for i, f := range formats {
+		names[i] = f.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// decodeOptions holds the flags of the decode command.
+type decodeOptions struct {
+	Derived       bool              // Add derived metrics to the output
+	File          string            // Batch input file, "-" for stdin
+	InputEncoding string            // Payload encoding, see inputEncodings
+	Output        string            // Output format, see outputFormats
+	Units         common.UnitSystem // Units of temperatures, pressure and altitude
+}
+
+// record is the output of one received payload: the reception metadata of
+// its tag.Envelope and the JSON encoding of its tag.DecodedData, extended
+// with the batch input line and derived metrics.
+type record struct {
+	Line      int                `json:"line,omitempty"`
+	Timestamp *time.Time         `json:"timestamp,omitempty"`
+	MAC       *common.MACAddress `json:"mac,omitempty"`
+	RSSI      *int               `json:"rssi,omitempty"`
+	Format    tag.DataFormat     `json:"format"`
+	Data      json.RawMessage    `json:"data,omitempty"`
+	Derived   json.RawMessage    `json:"derived,omitempty"` // JSON encoding of derive.Metrics
+
+	env *tag.Envelope // Envelope the record was built from, for influx output
+}
+
+// newRecord builds the output record of a received payload.
+func newRecord(env *tag.Envelope, opts decodeOptions) (*record, error) {
+	b, err := json.Marshal(env.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &record{MAC: env.Address, RSSI: env.RSSI, env: env}
+	if err := json.Unmarshal(b, rec); err != nil {
+		return nil, err
+	}
+	if !env.ReceivedAt.IsZero() {
+		ts := env.ReceivedAt
+		rec.Timestamp = &ts
+	}
+
+	if opts.Derived {
+		if rec.Derived, err = json.Marshal(derive.Compute(env.Data.Measurement())); err != nil {
+			return nil, err
+		}
+	}
+
+	return rec, nil
+}
+
+func handleDecode(hexStr string, opts decodeOptions) error {
+	if opts.Output == "" {
+		opts.Output = "json"
+		if opts.File != "" {
+			opts.Output = "ndjson"
+		}
+	}
+	if !slices.Contains(outputFormats, opts.Output) {
+		return fmt.Errorf("unknown output format %q (supported: %s)", opts.Output, strings.Join(outputFormats, ", "))
+	}
+	if opts.Units != common.Metric && !slices.Contains(unitOutputs, opts.Output) {
+		// The data object follows the JSON schema, which is metric
+		return fmt.Errorf("--units %s requires table or csv output; %s output is always in metric units", opts.Units, opts.Output)
+	}
+	if opts.InputEncoding != "" && !slices.Contains(inputEncodings, opts.InputEncoding) {
+		return fmt.Errorf("unknown input encoding %q (supported: %s)", opts.InputEncoding, strings.Join(inputEncodings, ", "))
+	}
+
+	if opts.File != "" {
+		return handleDecodeBatch(opts)
+	}
+
 	if hexStr == "" {
-		return fmt.Errorf("--hex flag is required")
+		return fmt.Errorf("--hex flag is required (or --file for batch input)")
 	}
 
-	// Decode hex string to bytes
-	data, err := hex.DecodeString(hexStr)
+	// Normalize the pasted payload to bytes
+	data, err := parsePayload(hexStr, opts.InputEncoding)
 	if err != nil {
-		return fmt.Errorf("invalid hex string: %w", err)
+		return err
 	}
 
 	// Decode RuuviTag data
@@ -92,14 +259,20 @@ func handleDecode(hexStr string) error {
 		return fmt.Errorf("failed to decode data: %w", err)
 	}
 
-	// Convert to JSON and print
-	output, err := json.MarshalIndent(decoded, "", "  ")
+	rec, err := newRecord(&tag.Envelope{Raw: data, Data: decoded}, opts)
 	if err != nil {
 		return fmt.Errorf("failed to marshal JSON: %w", err)
 	}
 
-	fmt.Println(string(output))
-	return nil
+	// Print in the requested output format
+	rw, err := newRecordWriter(os.Stdout, opts)
+	if err != nil {
+		return err
+	}
+	if err := rw.WriteRecord(rec); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return rw.Flush()
 }
 
 func handleEncode(jsonStr string) error {
@@ -107,14 +280,32 @@ func handleEncode(jsonStr string) error {
 		return fmt.Errorf("--json flag is required")
 	}
 
-	// Parse JSON input
-	var data tag.Format5Data
-	if err := json.Unmarshal([]byte(jsonStr), &data); err != nil {
+	// Parse JSON input: the output of decode carries a format byte,
+	// anything else is taken as Format 5 fields. Unknown keys are rejected,
+	// as they would otherwise encode as "not available".
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(jsonStr), &fields); err != nil {
 		return fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
-	// Encode to Format 5
-	encoded, err := tag.EncodeFormat5(&data)
+	data := &tag.DecodedData{Format: tag.Format5, Format5: &tag.Format5Data{}}
+	target := any(data.Format5)
+	if _, ok := fields["format"]; ok {
+		target = data
+	} else {
+		renamed, err := renameLegacyFormat5Keys(fields)
+		if err != nil {
+			return fmt.Errorf("failed to parse JSON: %w", err)
+		}
+		jsonStr = string(renamed)
+	}
+	dec := json.NewDecoder(strings.NewReader(jsonStr))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(target); err != nil {
+		return fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	encoded, err := tag.Encode(data)
 	if err != nil {
 		return fmt.Errorf("failed to encode data: %w", err)
 	}
@@ -123,3 +314,51 @@ func handleEncode(jsonStr string) error {
 	fmt.Println(hex.EncodeToString(encoded))
 	return nil
 }
+
+// legacyFormat5Keys maps the lower-cased Go field names of tag.Format5Data,
+// which encode accepted before the fields had JSON names, to the JSON names.
+var legacyFormat5Keys = func() map[string]string {
+	keys := make(map[string]string)
+	t := reflect.TypeFor[tag.Format5Data]()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		keys[strings.ToLower(f.Name)] = name
+	}
+	return keys
+}()
+
+// renameLegacyFormat5Keys returns bare Format 5 fields as JSON with the Go
+// field names of tag.Format5Data, matched case-insensitively as encoding/json
+// does, replaced by the JSON names. A legacy MACAddress given as an array of
+// six bytes is converted to its text form.
+func renameLegacyFormat5Keys(fields map[string]json.RawMessage) ([]byte, error) {
+	renamed := make(map[string]json.RawMessage, len(fields))
+	for key, value := range fields {
+		name, ok := legacyFormat5Keys[strings.ToLower(key)]
+		if !ok || name == key {
+			name = key
+		} else if _, ok := fields[name]; ok {
+			return nil, fmt.Errorf("field %q given both as %q and %q", name, key, name)
+		}
+
+		if name == "mac_address" && strings.HasPrefix(strings.TrimSpace(string(value)), "[") {
+			var mac common.MACAddress
+			if err := json.Unmarshal(value, (*[6]byte)(&mac)); err != nil {
+				return nil, fmt.Errorf("field %q: %w", key, err)
+			}
+			text, err := json.Marshal(mac)
+			if err != nil {
+				return nil, err
+			}
+			value = text
+		}
+		renamed[name] = value
+	}
+	return json.Marshal(renamed)
+}
+
+func handleSchema() error {
+	_, err := os.Stdout.Write(tag.JSONSchema())
+	return err
+}
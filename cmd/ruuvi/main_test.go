@@ -9,6 +9,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/marcgeld/ruuvi/common"
 	"github.com/marcgeld/ruuvi/tag"
 )
 
@@ -68,6 +69,9 @@ func TestRun_NoArgs_ShowsUsageAndErrors(t *testing.T) {
 	if !strings.Contains(stderr, "Usage: ruuvi") {
 		t.Fatalf("expected usage printed to stderr, got: %q", stderr)
 	}
+	if !strings.Contains(stderr, "Supported formats: 2, 3, 4, 5, 6, 8, C5, E1") {
+		t.Fatalf("expected supported formats in usage, got: %q", stderr)
+	}
 }
 
 func TestRun_UnknownCommand_ShowsUsageAndError(t *testing.T) {
@@ -118,7 +122,7 @@ func TestRun_Encode_NoJSONFlag_ReturnsError(t *testing.T) {
 
 func TestHandleDecode_InvalidHex_ReturnsError(t *testing.T) {
 	_, _ = captureStdoutStderr(func() {
-		err := handleDecode("nothex")
+		err := handleDecode("nothex", decodeOptions{})
 		if err == nil || !strings.Contains(err.Error(), "invalid hex string") {
 			t.Fatalf("expected invalid hex string error, got: %v", err)
 		}
@@ -134,6 +138,53 @@ func TestHandleEncode_InvalidJSON_ReturnsError(t *testing.T) {
 	})
 }
 
+func TestHandleEncode_UnknownKeys_ReturnsError(t *testing.T) {
+	for _, input := range []string{
+		`{"temperature_c":24.3,"humidity_pct":53.49}`,
+		`{"format":5,"data":{"temperature_c":24.3,"humidity":53.49}}`,
+	} {
+		out, _ := captureStdoutStderr(func() {
+			err := handleEncode(input)
+			if err == nil || !strings.Contains(err.Error(), "unknown field") {
+				t.Errorf("handleEncode(%s) expected unknown field error, got: %v", input, err)
+			}
+		})
+		if out != "" {
+			t.Errorf("handleEncode(%s) wrote %q, want no output", input, out)
+		}
+	}
+}
+
+func TestHandleEncode_LegacyKeys(t *testing.T) {
+	temp := 24.3
+	hum := 53.49
+	mac := common.MACAddress{0xCB, 0xB8, 0x33, 0x4C, 0x88, 0x4F}
+	expectedBytes, err := tag.EncodeFormat5(&tag.Format5Data{Temperature: &temp, Humidity: &hum, MACAddress: &mac})
+	if err != nil {
+		t.Fatalf("failed to encode expected bytes: %v", err)
+	}
+	expectedHex := hex.EncodeToString(expectedBytes)
+
+	for _, input := range []string{
+		`{"Temperature":24.3,"Humidity":53.49,"MACAddress":[203,184,51,76,136,79]}`,
+		`{"temperature":24.3,"humidity_percent":53.49,"MACAddress":"CB:B8:33:4C:88:4F"}`,
+	} {
+		out, _ := captureStdoutStderr(func() {
+			if err := handleEncode(input); err != nil {
+				t.Errorf("handleEncode(%s) returned error: %v", input, err)
+			}
+		})
+		if got := strings.TrimSpace(out); got != expectedHex {
+			t.Errorf("handleEncode(%s) = %q, want %q", input, got, expectedHex)
+		}
+	}
+
+	err = handleEncode(`{"Temperature":24.3,"temperature_c":25}`)
+	if err == nil {
+		t.Error("handleEncode with a field given twice expected error, got nil")
+	}
+}
+
 func TestHandleDecode_ValidFormat5(t *testing.T) {
 	// Create a Format5Data and encode to bytes
 	temp := 24.3
@@ -147,13 +198,13 @@ func TestHandleDecode_ValidFormat5(t *testing.T) {
 	hexStr := hex.EncodeToString(b)
 
 	out, _ := captureStdoutStderr(func() {
-		err := handleDecode(hexStr)
+		err := handleDecode(hexStr, decodeOptions{})
 		if err != nil {
 			t.Fatalf("handleDecode returned error: %v", err)
 		}
 	})
 
-	if !strings.Contains(out, "\"Format\": 5") {
+	if !strings.Contains(out, "\"format\": 5") {
 		t.Fatalf("expected decoded output to contain Format 5, got: %s", out)
 	}
 }
@@ -209,7 +260,7 @@ func TestRun_Decode_Success(t *testing.T) {
 		}
 	})
 
-	if !strings.Contains(out, "\"Format\": 5") {
+	if !strings.Contains(out, "\"format\": 5") {
 		t.Fatalf("expected decoded output to contain Format 5, got: %s", out)
 	}
 	if stderr != "" {
@@ -253,3 +304,273 @@ func TestRun_Encode_Success(t *testing.T) {
 		t.Fatalf("expected no stderr on successful run, got: %s", stderr)
 	}
 }
+
+func TestHandleDecode_RegisteredFormat(t *testing.T) {
+	tag.RegisterFormat(0xF0, tag.DecoderFunc(func(data []byte) (any, error) {
+		return map[string]int{"counter": int(data[1])}, nil
+	}))
+	defer tag.UnregisterFormat(0xF0)
+
+	out, _ := captureStdoutStderr(func() {
+		if err := handleDecode("F007", decodeOptions{}); err != nil {
+			t.Fatalf("handleDecode returned error: %v", err)
+		}
+	})
+
+	if !strings.Contains(out, "\"counter\": 7") {
+		t.Fatalf("expected custom decoder output, got: %s", out)
+	}
+}
+
+func TestRun_DecodeEncode_RoundTrip(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	for _, hexStr := range []string{
+		"0512fc5394c37c0004fffc040cac364200cdcbb8334c884f",
+		"06170c5668c79e007000c90501d9ffcd004c884f",
+		"c512fc5394c37cac364200cdcbb8334c884f",
+	} {
+		os.Args = []string{"ruuvi", "decode", "--hex", hexStr}
+		decoded, _ := captureStdoutStderr(func() {
+			if err := run(); err != nil {
+				t.Fatalf("decode returned error: %v", err)
+			}
+		})
+
+		os.Args = []string{"ruuvi", "encode", "--json", decoded}
+		encoded, _ := captureStdoutStderr(func() {
+			if err := run(); err != nil {
+				t.Fatalf("encode returned error: %v", err)
+			}
+		})
+
+		if got := strings.TrimSpace(encoded); got != hexStr {
+			t.Errorf("round trip of %s = %s", hexStr, got)
+		}
+	}
+}
+
+func TestRun_Schema(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{"ruuvi", "schema"}
+	out, _ := captureStdoutStderr(func() {
+		if err := run(); err != nil {
+			t.Fatalf("run() returned error: %v", err)
+		}
+	})
+
+	if !json.Valid([]byte(out)) || !strings.Contains(out, "\"$schema\"") {
+		t.Fatalf("expected JSON Schema output, got: %s", out)
+	}
+}
+
+func TestRun_Decode_Derived(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{"ruuvi", "decode", "--derived", "--hex", "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F"}
+	out, _ := captureStdoutStderr(func() {
+		if err := run(); err != nil {
+			t.Fatalf("run() returned error: %v", err)
+		}
+	})
+
+	var got struct {
+		Format  int                 `json:"format"`
+		Data    map[string]any      `json:"data"`
+		Derived map[string]*float64 `json:"derived"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, out)
+	}
+	if got.Format != 5 || got.Data["mac_address"] != "CB:B8:33:4C:88:4F" {
+		t.Fatalf("expected Format 5 data, got: %s", out)
+	}
+	if dp := got.Derived["dew_point_c"]; dp == nil || *dp < 14 || *dp > 15 {
+		t.Fatalf("expected dew point around 14.2 C, got: %s", out)
+	}
+
+	// Without --derived the output carries no derived metrics
+	os.Args = []string{"ruuvi", "decode", "--hex", "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F"}
+	out, _ = captureStdoutStderr(func() {
+		if err := run(); err != nil {
+			t.Fatalf("run() returned error: %v", err)
+		}
+	})
+	if strings.Contains(out, "derived") {
+		t.Fatalf("expected no derived metrics without --derived, got: %s", out)
+	}
+}
+
+func TestDecodeStream_ColumnsAndBadLines(t *testing.T) {
+	input := strings.Join([]string{
+		"# timestamp,mac,rssi,payload",
+		"2024-05-01T12:00:00Z,CB:B8:33:4C:88:4F,-71,0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F",
+		"",
+		"nothex",
+		"1714564800123 -65 0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F",
+		"0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F",
+		"bogus 0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F",
+	}, "\n")
+
+	var out, errOut bytes.Buffer
+	err := decodeStream(strings.NewReader(input), &out, &errOut, decodeOptions{Output: "ndjson"})
+	if err == nil || !strings.Contains(err.Error(), "2 of 5 lines failed") {
+		t.Fatalf("expected 2 of 5 lines to fail, got: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 NDJSON records, got %d:\n%s", len(lines), out.String())
+	}
+
+	var recs []map[string]any
+	for _, line := range lines {
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", line, err)
+		}
+		recs = append(recs, rec)
+	}
+
+	if recs[0]["line"] != 2.0 || recs[0]["timestamp"] != "2024-05-01T12:00:00Z" ||
+		recs[0]["mac"] != "CB:B8:33:4C:88:4F" || recs[0]["rssi"] != -71.0 || recs[0]["format"] != 5.0 {
+		t.Errorf("unexpected first record: %s", lines[0])
+	}
+	if recs[1]["line"] != 5.0 || recs[1]["timestamp"] != "2024-05-01T12:00:00.123Z" || recs[1]["rssi"] != -65.0 {
+		t.Errorf("unexpected second record: %s", lines[1])
+	}
+	if _, ok := recs[2]["timestamp"]; ok || recs[2]["line"] != 6.0 {
+		t.Errorf("expected bare payload record without timestamp, got: %s", lines[2])
+	}
+
+	stderr := errOut.String()
+	if !strings.Contains(stderr, "line 4: invalid hex string") {
+		t.Errorf("expected line 4 to be reported, got: %s", stderr)
+	}
+	if !strings.Contains(stderr, `line 7: unrecognized column "bogus"`) {
+		t.Errorf("expected line 7 to be reported, got: %s", stderr)
+	}
+}
+
+func TestDecodeStream_SpaceSeparatedPayload(t *testing.T) {
+	input := strings.Join([]string{
+		"05 12 FC 53 94 C3 7C 00 04 FF FC 04 0C AC 36 42 00 CD CB B8 33 4C 88 4F",
+		"1714564800 CB:B8:33:4C:88:4F -71 0x05 0x12 0xFC 0x53 0x94 0xC3 0x7C 0x00 0x04 0xFF 0xFC 0x04 0x0C 0xAC 0x36 0x42 0x00 0xCD 0xCB 0xB8 0x33 0x4C 0x88 0x4F",
+		"-65 99 04 05 12 FC 53 94 C3 7C 00 04 FF FC 04 0C AC 36 42 00 CD CB B8 33 4C 88 4F",
+	}, "\n")
+
+	var out, errOut bytes.Buffer
+	if err := decodeStream(strings.NewReader(input), &out, &errOut, decodeOptions{Output: "ndjson"}); err != nil {
+		t.Fatalf("decodeStream failed: %v\n%s", err, errOut.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 NDJSON records, got %d:\n%s", len(lines), out.String())
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, `"temperature_c":24.3,`) {
+			t.Errorf("record %d: unexpected data: %s", i+1, line)
+		}
+	}
+	if !strings.Contains(lines[1], `"mac":"CB:B8:33:4C:88:4F","rssi":-71`) || !strings.Contains(lines[2], `"rssi":-65`) {
+		t.Errorf("unexpected reception metadata:\n%s", out.String())
+	}
+}
+
+func TestRun_Decode_File(t *testing.T) {
+	path := t.TempDir() + "/capture.txt"
+	content := "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F\n" +
+		"0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{"ruuvi", "decode", "--derived", "--file", path}
+	out, stderr := captureStdoutStderr(func() {
+		if err := run(); err != nil {
+			t.Fatalf("run() returned error: %v", err)
+		}
+	})
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 NDJSON records, got %d:\n%s", len(lines), out)
+	}
+	if !strings.Contains(lines[1], `"line":2`) || !strings.Contains(lines[1], `"derived":{`) {
+		t.Errorf("unexpected record: %s", lines[1])
+	}
+	if stderr != "" {
+		t.Errorf("expected no errors, got: %s", stderr)
+	}
+
+	os.Args = []string{"ruuvi", "decode", "--file", t.TempDir() + "/missing.txt"}
+	_, _ = captureStdoutStderr(func() {
+		if err := run(); err == nil {
+			t.Fatal("expected error for missing input file")
+		}
+	})
+}
+
+func TestRun_Decode_Stdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.WriteString("-70\t0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F\n")
+	_ = w.Close()
+
+	oldArgs, oldStdin := os.Args, os.Stdin
+	defer func() { os.Args, os.Stdin = oldArgs, oldStdin }()
+
+	os.Args = []string{"ruuvi", "decode", "--file", "-"}
+	os.Stdin = r
+	out, _ := captureStdoutStderr(func() {
+		if err := run(); err != nil {
+			t.Fatalf("run() returned error: %v", err)
+		}
+	})
+
+	if !strings.HasPrefix(out, `{"line":1,"rssi":-70,"format":5,"data":{`) {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
+
+func TestRun_Explain(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{"ruuvi", "explain", "--hex", "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F"}
+	out, _ := captureStdoutStderr(func() {
+		if err := run(); err != nil {
+			t.Fatalf("run() returned error: %v", err)
+		}
+	})
+
+	if !strings.HasPrefix(out, "Data Format 5, 24 bytes\n") {
+		t.Errorf("unexpected header: %s", out)
+	}
+	for _, want := range []string{
+		"1-2     12 FC              temperature_c         4860   × 0.005   24.3 °C            n/a = 0x8000\n",
+		"13-14   AC 36              tx_power_dbm          22     × 2 - 40  4 dBm              bits 4-0, n/a = 0x1F\n",
+		"18-23   CB B8 33 4C 88 4F  mac_address                            CB:B8:33:4C:88:4F  n/a = all 0xFF\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	os.Args = []string{"ruuvi", "explain", "--hex", "0512"}
+	_, _ = captureStdoutStderr(func() {
+		if err := run(); err == nil || !strings.Contains(err.Error(), "failed to explain data") {
+			t.Errorf("expected explain error for short payload, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,520 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/marcgeld/ruuvi/common"
+	"github.com/marcgeld/ruuvi/influx"
+	"github.com/marcgeld/ruuvi/tag"
+)
+
+// outputFormats lists the values accepted by the --output flag of decode.
+var outputFormats = []string{"json", "ndjson", "yaml", "csv", "table", "influx"}
+
+// column describes a record field in CSV and table output.
+type column struct {
+	Key   string // JSON key
+	Label string // Human-readable name
+	Unit  string // Unit symbol, empty if unitless
+}
+
+// recordColumns are the top-level fields of a record.
+var recordColumns = []column{
+	{"line", "Line", ""},
+	{"timestamp", "Timestamp", ""},
+	{"mac", "MAC", ""},
+	{"rssi", "RSSI", "dBm"},
+	{"format", "Format", ""},
+}
+
+// dataColumns are the fields of all built-in data formats, in the order they
+// appear in CSV output. The CSV header is the same for every format so that
+// captures mixing formats load into a single table.
+var dataColumns = []column{
+	{"temperature_c", "Temperature", "°C"},
+	{"humidity_percent", "Humidity", "%"},
+	{"pressure_pa", "Pressure", "Pa"},
+	{"acceleration_x_g", "Acceleration X", "g"},
+	{"acceleration_y_g", "Acceleration Y", "g"},
+	{"acceleration_z_g", "Acceleration Z", "g"},
+	{"battery_voltage_mv", "Battery voltage", "mV"},
+	{"tx_power_dbm", "TX power", "dBm"},
+	{"movement_counter", "Movement counter", ""},
+	{"measurement_sequence", "Measurement sequence", ""},
+	{"pm1_0_ug_m3", "PM1.0", "µg/m³"},
+	{"pm2_5_ug_m3", "PM2.5", "µg/m³"},
+	{"pm4_0_ug_m3", "PM4.0", "µg/m³"},
+	{"pm10_0_ug_m3", "PM10", "µg/m³"},
+	{"co2_ppm", "CO2", "ppm"},
+	{"voc_index", "VOC index", ""},
+	{"nox_index", "NOx index", ""},
+	{"luminosity_lux", "Luminosity", "lx"},
+	{"sound_instant_dba", "Sound (instant)", "dBA"},
+	{"sound_average_dba", "Sound (average)", "dBA"},
+	{"sound_peak_dba", "Sound (peak)", "dBA"},
+	{"flags", "Flags", ""},
+	{"tag_id", "Tag ID", ""},
+	{"mac_address", "MAC address", ""},
+	{"mac_suffix", "MAC suffix", ""},
+}
+
+// derivedColumns are the fields of derive.Metrics.
+var derivedColumns = []column{
+	{"equilibrium_vapor_pressure_pa", "Equilibrium vapor pressure", "Pa"},
+	{"dew_point_c", "Dew point", "°C"},
+	{"absolute_humidity_g_m3", "Absolute humidity", "g/m³"},
+	{"vapor_pressure_deficit_pa", "Vapor pressure deficit", "Pa"},
+	{"air_density_kg_m3", "Air density", "kg/m³"},
+	{"heat_index_c", "Heat index", "°C"},
+	{"altitude_m", "Altitude", "m"},
+}
+
+// columnsByKey indexes all known columns, including the imperial ones, by
+// JSON key.
+var columnsByKey = func() map[string]column {
+	m := make(map[string]column)
+	for _, cols := range [][]column{recordColumns, dataColumns, derivedColumns} {
+		for _, c := range cols {
+			m[c.Key] = c
+		}
+	}
+	for _, conv := range imperialConversions {
+		m[conv.column.Key] = conv.column
+	}
+	return m
+}()
+
+// recordWriter writes decoded records in one output format.
+type recordWriter interface {
+	WriteRecord(rec *record) error
+
+	// Flush writes any buffered output.
+	Flush() error
+}
+
+// newRecordWriter returns a recordWriter for opts.Output writing to w.
+func newRecordWriter(w io.Writer, opts decodeOptions) (recordWriter, error) {
+	switch opts.Output {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return &jsonWriter{enc: enc}, nil
+	case "ndjson":
+		return &jsonWriter{enc: json.NewEncoder(w)}, nil
+	case "yaml":
+		return &yamlWriter{w: w}, nil
+	case "csv":
+		return newCSVWriter(w, opts.Derived, opts.Units), nil
+	case "table":
+		return &tableWriter{w: w, units: opts.Units}, nil
+	case "influx":
+		return &influxWriter{w: w, enc: influx.Encoder{Derived: opts.Derived}}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q (supported: %s)", opts.Output, strings.Join(outputFormats, ", "))
+	}
+}
+
+// jsonWriter writes records as JSON, one value per record.
+type jsonWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonWriter) WriteRecord(rec *record) error {
+	return j.enc.Encode(rec)
+}
+
+func (j *jsonWriter) Flush() error {
+	return nil
+}
+
+// yamlWriter writes records as a stream of YAML documents. Fields keep the
+// order of the JSON output; strings are always double-quoted so that MAC
+// addresses and timestamps are not mistaken for numbers or dates.
+type yamlWriter struct {
+	w io.Writer
+}
+
+func (y *yamlWriter) WriteRecord(rec *record) error {
+	doc, err := orderedFields(rec)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("---\n")
+	writeYAMLMapping(&b, doc, 0)
+	_, err = io.WriteString(y.w, b.String())
+	return err
+}
+
+func (y *yamlWriter) Flush() error {
+	return nil
+}
+
+// plainYAMLKey matches keys that need no quoting in YAML.
+var plainYAMLKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func writeYAMLMapping(b *strings.Builder, fields []field, indent int) {
+	pad := strings.Repeat(" ", indent)
+	for _, f := range fields {
+		key := f.Key
+		if !plainYAMLKey.MatchString(key) {
+			key = strconv.Quote(key)
+		}
+
+		switch v := f.Value.(type) {
+		case []field:
+			if len(v) == 0 {
+				fmt.Fprintf(b, "%s%s: {}\n", pad, key)
+				continue
+			}
+			fmt.Fprintf(b, "%s%s:\n", pad, key)
+			writeYAMLMapping(b, v, indent+2)
+		case []any:
+			if len(v) == 0 {
+				fmt.Fprintf(b, "%s%s: []\n", pad, key)
+				continue
+			}
+			fmt.Fprintf(b, "%s%s:\n", pad, key)
+			writeYAMLSequence(b, v, indent+2)
+		default:
+			fmt.Fprintf(b, "%s%s: %s\n", pad, key, yamlScalar(v))
+		}
+	}
+}
+
+func writeYAMLSequence(b *strings.Builder, items []any, indent int) {
+	pad := strings.Repeat(" ", indent)
+	for _, item := range items {
+		switch v := item.(type) {
+		case []field:
+			fmt.Fprintf(b, "%s-\n", pad)
+			writeYAMLMapping(b, v, indent+2)
+		case []any:
+			fmt.Fprintf(b, "%s-\n", pad)
+			writeYAMLSequence(b, v, indent+2)
+		default:
+			fmt.Fprintf(b, "%s- %s\n", pad, yamlScalar(v))
+		}
+	}
+}
+
+// yamlScalar formats a JSON scalar as a YAML scalar. The escapes produced by
+// strconv.Quote are all valid in YAML double-quoted scalars.
+func yamlScalar(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// csvWriter writes records as CSV with a header row. The columns are fixed,
+// see dataColumns; fields of custom formats are not included.
+type csvWriter struct {
+	w       *csv.Writer
+	columns []string
+	units   common.UnitSystem
+	header  bool
+}
+
+func newCSVWriter(w io.Writer, derived bool, units common.UnitSystem) *csvWriter {
+	cols := append(append([]column(nil), recordColumns...), dataColumns...)
+	if derived {
+		cols = append(cols, derivedColumns...)
+	}
+	cols = unitColumns(cols, units)
+
+	keys := make([]string, len(cols))
+	for i, c := range cols {
+		keys[i] = c.Key
+	}
+
+	return &csvWriter{w: csv.NewWriter(w), columns: keys, units: units}
+}
+
+func (c *csvWriter) WriteRecord(rec *record) error {
+	if !c.header {
+		if err := c.w.Write(c.columns); err != nil {
+			return err
+		}
+		c.header = true
+	}
+
+	rec, err := convertRecord(rec, c.units)
+	if err != nil {
+		return err
+	}
+	cells, err := flattenRecord(rec)
+	if err != nil {
+		return err
+	}
+
+	row := make([]string, len(c.columns))
+	for i, key := range c.columns {
+		row[i] = cells[key]
+	}
+	return c.w.Write(row)
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// flattenRecord returns the fields of a record and of its data and derived
+// objects as CSV cells keyed by JSON key. Unavailable values are empty.
+func flattenRecord(rec *record) (map[string]string, error) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(b, &top); err != nil {
+		return nil, err
+	}
+
+	cells := make(map[string]string)
+	for key, raw := range top {
+		if (key == "data" || key == "derived") && bytes.HasPrefix(raw, []byte("{")) {
+			var nested map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &nested); err != nil {
+				return nil, err
+			}
+			for k, v := range nested {
+				cells[k] = csvCell(v)
+			}
+			continue
+		}
+		cells[key] = csvCell(raw)
+	}
+
+	return cells, nil
+}
+
+// csvCell formats a JSON value as a CSV cell: strings unquoted, null empty and
+// anything else as its JSON text.
+func csvCell(raw json.RawMessage) string {
+	var s string
+	switch {
+	case string(raw) == "null":
+		return ""
+	case json.Unmarshal(raw, &s) == nil:
+		return s
+	default:
+		return string(raw)
+	}
+}
+
+// tableWriter writes each record as an aligned two-column table of
+// human-readable field names and values with units, with a blank line
+// between records.
+type tableWriter struct {
+	w       io.Writer
+	units   common.UnitSystem
+	written bool
+}
+
+func (t *tableWriter) WriteRecord(rec *record) error {
+	rec, err := convertRecord(rec, t.units)
+	if err != nil {
+		return err
+	}
+	doc, err := orderedFields(rec)
+	if err != nil {
+		return err
+	}
+
+	if t.written {
+		if _, err := fmt.Fprintln(t.w); err != nil {
+			return err
+		}
+	}
+	t.written = true
+
+	tw := tabwriter.NewWriter(t.w, 0, 0, 2, ' ', 0)
+	writeTableRows(tw, "", doc)
+	return tw.Flush()
+}
+
+func (t *tableWriter) Flush() error {
+	return nil
+}
+
+// writeTableRows writes one row per field. The data and derived objects are
+// inlined; other nested objects are written with dotted names.
+func writeTableRows(w io.Writer, prefix string, fields []field) {
+	for _, f := range fields {
+		if nested, ok := f.Value.([]field); ok {
+			p := ""
+			if prefix != "" || (f.Key != "data" && f.Key != "derived") {
+				p = prefix + f.Key + "."
+			}
+			writeTableRows(w, p, nested)
+			continue
+		}
+
+		label, unit := prefix+f.Key, ""
+		if c, ok := columnsByKey[f.Key]; ok && prefix == "" {
+			label, unit = c.Label, c.Unit
+		}
+
+		value := tableValue(f.Value)
+		if f.Key == "format" && prefix == "" {
+			if n, err := strconv.ParseUint(value, 10, 8); err == nil {
+				value = tag.DataFormat(n).String()
+			}
+		}
+		if f.Value == nil {
+			unit = ""
+		}
+		if unit != "" {
+			value += " " + unit
+		}
+
+		fmt.Fprintf(w, "%s\t%s\n", label, value)
+	}
+}
+
+// tableValue formats a JSON value for table output. Numbers are rounded to
+// four decimal places, which keeps every decoded value intact but shortens
+// derived metrics.
+func tableValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "n/a"
+	case string:
+		return v
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return v.String()
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return v.String()
+		}
+		return strconv.FormatFloat(math.Round(f*1e4)/1e4, 'f', -1, 64)
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = tableValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// influxWriter writes records as InfluxDB line protocol, one line per
+// record. Records without any available value are skipped, as line protocol
+// cannot represent them.
+type influxWriter struct {
+	w   io.Writer
+	enc influx.Encoder
+	buf []byte
+}
+
+func (i *influxWriter) WriteRecord(rec *record) error {
+	var err error
+	i.buf, err = i.enc.Append(i.buf[:0], rec.env)
+	if errors.Is(err, influx.ErrNoFields) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = i.w.Write(i.buf)
+	return err
+}
+
+func (i *influxWriter) Flush() error {
+	return nil
+}
+
+// field is a key/value pair of a JSON object, in document order.
+type field struct {
+	Key string
+
+	// Value is nil, bool, json.Number, string, []field for objects or []any
+	// for arrays.
+	Value any
+}
+
+// orderedFields returns the fields of the JSON encoding of v in document
+// order, which encoding/json does not preserve when decoding into maps.
+func orderedFields(v any) ([]field, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	value, err := readJSONValue(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, ok := value.([]field)
+	if !ok {
+		return nil, fmt.Errorf("expected JSON object, got %T", value)
+	}
+	return fields, nil
+}
+
+// readJSONValue reads the next JSON value from dec, keeping object fields in
+// order.
+func readJSONValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		fields := []field{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := readJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, field{Key: key.(string), Value: value})
+		}
+		_, err := dec.Token() // Closing brace
+		return fields, err
+
+	case json.Delim('['):
+		items := []any{}
+		for dec.More() {
+			value, err := readJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		_, err := dec.Token() // Closing bracket
+		return items, err
+
+	default:
+		return tok, nil
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/marcgeld/ruuvi/common"
+	"github.com/marcgeld/ruuvi/tag"
+)
+
+// decodeTestRecord decodes a hex payload into an output record.
+func decodeTestRecord(t *testing.T, hexStr string, opts decodeOptions) *record {
+	t.Helper()
+	data, err := hex.DecodeString(hexStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := tag.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := newRecord(&tag.Envelope{Raw: data, Data: decoded}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+// writeTestRecords writes records with the record writer of opts.
+func writeTestRecords(t *testing.T, opts decodeOptions, recs ...*record) string {
+	t.Helper()
+	var buf bytes.Buffer
+	rw, err := newRecordWriter(&buf, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rec := range recs {
+		if err := rw.WriteRecord(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestDataColumns_CoverBuiltinFormats(t *testing.T) {
+	for _, v := range []any{
+		tag.Format2Data{}, tag.Format3Data{}, tag.Format4Data{}, tag.Format5Data{},
+		tag.Format6Data{}, tag.Format8Data{}, tag.FormatC5Data{}, tag.FormatE1Data{},
+	} {
+		fields, err := orderedFields(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range fields {
+			if _, ok := columnsByKey[f.Key]; !ok {
+				t.Errorf("%T field %q has no column", v, f.Key)
+			}
+		}
+	}
+}
+
+func TestDataColumns_UnitsMatchFields(t *testing.T) {
+	for _, format := range []tag.DataFormat{
+		tag.Format2, tag.Format3, tag.Format4, tag.Format5,
+		tag.Format6, tag.Format8, tag.FormatC5, tag.FormatE1,
+	} {
+		for _, f := range tag.Fields(format) {
+			if c, ok := columnsByKey[f.Name]; ok && c.Unit != f.Unit {
+				t.Errorf("column %s has unit %q, format %s field has %q", c.Key, c.Unit, format, f.Unit)
+			}
+		}
+	}
+}
+
+func TestYAMLWriter(t *testing.T) {
+	rec := decodeTestRecord(t, "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F", decodeOptions{})
+	got := writeTestRecords(t, decodeOptions{Output: "yaml"}, rec, rec)
+
+	want := `---
+format: 5
+data:
+  temperature_c: 24.3
+  humidity_percent: 53.49
+  pressure_pa: 100044
+  acceleration_x_g: 0.004
+  acceleration_y_g: -0.004
+  acceleration_z_g: 1.036
+  battery_voltage_mv: 2977
+  tx_power_dbm: 4
+  movement_counter: 66
+  measurement_sequence: 205
+  mac_address: "CB:B8:33:4C:88:4F"
+`
+	if got != want+want {
+		t.Errorf("unexpected YAML:\n%s", got)
+	}
+}
+
+func TestYAMLWriter_NestedValues(t *testing.T) {
+	var b strings.Builder
+	writeYAMLMapping(&b, []field{
+		{Key: "empty", Value: []field{}},
+		{Key: "list", Value: []any{json.Number("1"), "a\nb", nil, []field{{Key: "x", Value: true}}}},
+		{Key: "odd key", Value: "v"},
+	}, 0)
+
+	want := `empty: {}
+list:
+  - 1
+  - "a\nb"
+  - null
+  -
+    x: true
+"odd key": "v"
+`
+	if b.String() != want {
+		t.Errorf("unexpected YAML:\n%s", b.String())
+	}
+}
+
+func TestCSVWriter_StableHeader(t *testing.T) {
+	f5 := decodeTestRecord(t, "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F", decodeOptions{})
+	f6 := decodeTestRecord(t, "06170C5668C79E007000C90501D9FFCD004C884F", decodeOptions{})
+	f6.Line = 2
+
+	rows, err := csv.NewReader(strings.NewReader(writeTestRecords(t, decodeOptions{Output: "csv"}, f5, f6))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected header and 2 rows, got %d", len(rows))
+	}
+
+	header := rows[0]
+	if len(header) != len(recordColumns)+len(dataColumns) {
+		t.Fatalf("unexpected header: %v", header)
+	}
+	cell := func(row []string, key string) string {
+		for i, k := range header {
+			if k == key {
+				return row[i]
+			}
+		}
+		t.Fatalf("column %q not in header", key)
+		return ""
+	}
+
+	if cell(rows[1], "format") != "5" || cell(rows[1], "mac_address") != "CB:B8:33:4C:88:4F" ||
+		cell(rows[1], "co2_ppm") != "" || cell(rows[1], "line") != "" {
+		t.Errorf("unexpected Format 5 row: %v", rows[1])
+	}
+	if cell(rows[2], "format") != "6" || cell(rows[2], "co2_ppm") == "" ||
+		cell(rows[2], "mac_suffix") != "4C:88:4F" || cell(rows[2], "line") != "2" {
+		t.Errorf("unexpected Format 6 row: %v", rows[2])
+	}
+
+	// Derived metrics add columns
+	derived := writeTestRecords(t, decodeOptions{Output: "csv", Derived: true}, f5)
+	if header := strings.SplitN(derived, "\n", 2)[0]; !strings.HasSuffix(header, ",heat_index_c,altitude_m") {
+		t.Errorf("expected derived columns in header, got: %s", header)
+	}
+}
+
+func TestTableWriter(t *testing.T) {
+	rec := decodeTestRecord(t, "C512FC5394C37CAC364200CDCBB8334C884F", decodeOptions{Derived: true})
+	rssi := -70
+	rec.RSSI = &rssi
+
+	got := writeTestRecords(t, decodeOptions{Output: "table"}, rec)
+	for _, want := range []string{
+		"RSSI                        -70 dBm\n",
+		"Format                      C5\n",
+		"Temperature                 24.3 °C\n",
+		"Pressure                    100044 Pa\n",
+		"MAC address                 CB:B8:33:4C:88:4F\n",
+		"Dew point                   14.2476 °C\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected table to contain %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestRecord_ImperialUnits(t *testing.T) {
+	rec := decodeTestRecord(t, "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F", decodeOptions{Derived: true, Units: common.Imperial})
+
+	// The data object keeps the metric keys of the JSON schema
+	if !strings.HasPrefix(string(rec.Data), `{"temperature_c":24.3,`) {
+		t.Errorf("record data converted: %s", rec.Data)
+	}
+
+	conv, err := convertRecord(rec, common.Imperial)
+	if err != nil {
+		t.Fatalf("convertRecord failed: %v", err)
+	}
+	var data, derived map[string]any
+	if err := json.Unmarshal(conv.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(conv.Derived, &derived); err != nil {
+		t.Fatal(err)
+	}
+	if data["temperature_f"] != 75.74 || data["pressure_inhg"] != 29.543 || data["humidity_percent"] != 53.49 {
+		t.Errorf("unexpected imperial data: %s", conv.Data)
+	}
+	if _, ok := data["temperature_c"]; ok {
+		t.Errorf("metric key left in imperial data: %s", conv.Data)
+	}
+	if derived["dew_point_f"] == nil || derived["altitude_ft"] == nil || derived["dew_point_c"] != nil {
+		t.Errorf("unexpected imperial derived metrics: %s", conv.Derived)
+	}
+
+	// Fields keep their order
+	if !strings.HasPrefix(string(conv.Data), `{"temperature_f":75.74,"humidity_percent":53.49,"pressure_inhg":29.543,`) {
+		t.Errorf("unexpected field order: %s", conv.Data)
+	}
+
+	opts := decodeOptions{Output: "csv", Derived: true, Units: common.Imperial}
+	out := writeTestRecords(t, opts, rec)
+	rows := strings.Split(out, "\n")
+	if !strings.Contains(rows[0], ",format,temperature_f,humidity_percent,pressure_inhg,") || !strings.HasSuffix(rows[0], ",heat_index_f,altitude_ft") {
+		t.Errorf("unexpected imperial CSV header: %s", rows[0])
+	}
+	if !strings.Contains(rows[1], ",5,75.74,53.49,29.543,") {
+		t.Errorf("unexpected imperial CSV row: %s", rows[1])
+	}
+
+	table := writeTestRecords(t, decodeOptions{Output: "table", Units: common.Imperial}, rec)
+	for _, want := range []string{"Temperature                 75.74 °F\n", "Pressure                    29.543 inHg\n"} {
+		if !strings.Contains(table, want) {
+			t.Errorf("expected table to contain %q, got:\n%s", want, table)
+		}
+	}
+}
+
+func TestRecord_ImperialUnavailable(t *testing.T) {
+	// Format 5 with all values set to "not available"
+	rec := decodeTestRecord(t, "058000FFFFFFFF800080008000FFFFFFFFFFFFFFFFFFFFFF", decodeOptions{Units: common.Imperial})
+	conv, err := convertRecord(rec, common.Imperial)
+	if err != nil {
+		t.Fatalf("convertRecord failed: %v", err)
+	}
+	if !strings.Contains(string(conv.Data), `"temperature_f":null`) || !strings.Contains(string(conv.Data), `"pressure_inhg":null`) {
+		t.Errorf("unexpected imperial data: %s", conv.Data)
+	}
+}
+
+func TestInfluxWriter(t *testing.T) {
+	rec := decodeTestRecord(t, "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F", decodeOptions{})
+	empty := decodeTestRecord(t, "058000FFFFFFFF800080008000FFFFFFFFFFFFFFFFFFFFFF", decodeOptions{})
+
+	got := writeTestRecords(t, decodeOptions{Output: "influx", Derived: true}, rec, empty, rec)
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if len(lines) != 2 || lines[0] != lines[1] {
+		t.Fatalf("expected two identical lines, got:\n%s", got)
+	}
+	if !strings.HasPrefix(lines[0], "ruuvi,format=5,mac=CB:B8:33:4C:88:4F temperature_c=24.3,") ||
+		!strings.Contains(lines[0], ",pressure_pa=100044i,") || !strings.Contains(lines[0], ",dew_point_c=") {
+		t.Errorf("unexpected line: %s", lines[0])
+	}
+}
+
+func TestRun_Decode_Output(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{"ruuvi", "decode", "--output", "csv", "--hex", "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F"}
+	out, _ := captureStdoutStderr(func() {
+		if err := run(); err != nil {
+			t.Fatalf("run() returned error: %v", err)
+		}
+	})
+	if !strings.HasPrefix(out, "line,timestamp,mac,rssi,format,temperature_c,") || strings.Count(out, "\n") != 2 {
+		t.Errorf("unexpected CSV output: %s", out)
+	}
+
+	os.Args = []string{"ruuvi", "decode", "--output", "xml", "--hex", "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F"}
+	_, _ = captureStdoutStderr(func() {
+		if err := run(); err == nil || !strings.Contains(err.Error(), `unknown output format "xml"`) {
+			t.Errorf("expected unknown output format error, got: %v", err)
+		}
+	})
+
+	for _, output := range []string{"json", "ndjson", "yaml", "influx"} {
+		os.Args = []string{"ruuvi", "decode", "--output", output, "--units", "imperial", "--hex", "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F"}
+		_, _ = captureStdoutStderr(func() {
+			if err := run(); err == nil || !strings.Contains(err.Error(), "requires table or csv output") {
+				t.Errorf("%s: expected units error, got: %v", output, err)
+			}
+		})
+	}
+
+	os.Args = []string{"ruuvi", "decode", "--units", "nautical", "--hex", "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F"}
+	_, _ = captureStdoutStderr(func() {
+		if err := run(); err == nil || !strings.Contains(err.Error(), `unknown unit system "nautical"`) {
+			t.Errorf("expected unknown unit system error, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/marcgeld/ruuvi/metrics"
+)
+
+// serveMetricsOptions holds the flags of the serve-metrics command.
+type serveMetricsOptions struct {
+	Listen        string        // Address of the HTTP server
+	File          string        // Input file, "-" for stdin
+	InputEncoding string        // Payload encoding, see inputEncodings
+	StaleTimeout  time.Duration // Drop tags not heard from for this long, 0 to keep them
+}
+
+// handleServeMetrics reads payloads in the batch input format of decode,
+// keeps the latest reading of every tag and serves them on /metrics until
+// interrupted. The server keeps running after the input ends.
+func handleServeMetrics(opts serveMetricsOptions) error {
+	in := os.Stdin
+	if opts.File != "-" {
+		f, err := os.Open(opts.File)
+		if err != nil {
+			return fmt.Errorf("failed to open input: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		in = f
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	collector := metrics.NewCollector(opts.StaleTimeout)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", collector)
+
+	ln, err := net.Listen("tcp", opts.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.Serve(ln) }()
+	fmt.Fprintf(os.Stderr, "Serving metrics on http://%s/metrics\n", ln.Addr())
+
+	go func() {
+		if err := feedCollector(in, collector, os.Stderr, opts.InputEncoding); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("metrics server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to shut down metrics server: %w", err)
+	}
+	return nil
+}
+
+// feedCollector decodes the lines of r into c until r ends. Lines that fail
+// to decode are counted by c and reported on errw. Blank lines and lines
+// starting with '#' are ignored.
+func feedCollector(r io.Reader, c *metrics.Collector, errw io.Writer, inputEncoding string) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		env, err := decodeEnvelope(line, inputEncoding)
+		if err != nil {
+			c.ObserveError(err)
+			fmt.Fprintf(errw, "line %d: %v\n", lineNo, err)
+			continue
+		}
+		if !c.Observe(env) {
+			fmt.Fprintf(errw, "line %d: no MAC address to identify the tag, add a MAC column\n", lineNo)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/marcgeld/ruuvi/metrics"
+)
+
+func TestFeedCollector(t *testing.T) {
+	input := strings.Join([]string{
+		"# capture",
+		"2024-05-01T12:00:00Z,CB:B8:33:4C:88:4F,-71,0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F",
+		"CB:B8:33:4C:88:50 06170C5668C79E007000C90501D9FFCD004C884F",
+		"06170C5668C79E007000C90501D9FFCD004C884F",
+		"4212",
+		"not hex",
+	}, "\n")
+
+	c := metrics.NewCollector(0)
+	var errw bytes.Buffer
+	if err := feedCollector(strings.NewReader(input), c, &errw, "auto"); err != nil {
+		t.Fatalf("feedCollector() error = %v", err)
+	}
+
+	for _, want := range []string{"line 4: no MAC address", "line 5: ", "line 6: "} {
+		if !strings.Contains(errw.String(), want) {
+			t.Errorf("expected %q on stderr, got:\n%s", want, errw.String())
+		}
+	}
+
+	var out bytes.Buffer
+	if err := c.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`ruuvi_rssi_dbm{mac="CB:B8:33:4C:88:4F",format="5"} -71`,
+		`ruuvi_last_seen_timestamp_seconds{mac="CB:B8:33:4C:88:4F",format="5"} 1.7145648e+09`,
+		`ruuvi_temperature_celsius{mac="CB:B8:33:4C:88:50",format="6"} 29.5`,
+		`ruuvi_decode_errors_total{type="unknown_format"} 1`,
+		`ruuvi_decode_errors_total{type="other"} 1`,
+	} {
+		if !strings.Contains(out.String(), want+"\n") {
+			t.Errorf("expected metrics to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
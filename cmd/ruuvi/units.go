@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/marcgeld/ruuvi/common"
+)
+
+// metersPerFoot converts barometric altitude to feet.
+const metersPerFoot = 0.3048
+
+// unitConversion replaces a metric field of the decode output.
+type unitConversion struct {
+	column  column // Replacing column
+	convert func(float64) float64
+}
+
+// unitOutputs are the output formats supporting --units other than metric.
+// The JSON, YAML and influx outputs keep the metric keys of the JSON schema.
+var unitOutputs = []string{"csv", "table"}
+
+// imperialConversions are applied to data and derived fields with
+// --units imperial, keyed by metric JSON key. Fields without a common
+// imperial unit, such as humidity or particulate matter, are kept.
+var imperialConversions = map[string]unitConversion{
+	"temperature_c": {column{"temperature_f", "Temperature", "°F"}, fahrenheit},
+	"pressure_pa": {column{"pressure_inhg", "Pressure", "inHg"}, func(pa float64) float64 {
+		return common.Pressure{Pascals: int(math.Round(pa))}.InchesOfMercury()
+	}},
+	"dew_point_c":  {column{"dew_point_f", "Dew point", "°F"}, fahrenheit},
+	"heat_index_c": {column{"heat_index_f", "Heat index", "°F"}, fahrenheit},
+	"altitude_m":   {column{"altitude_ft", "Altitude", "ft"}, func(m float64) float64 { return m / metersPerFoot }},
+}
+
+func fahrenheit(c float64) float64 {
+	return common.Temperature{Celsius: c}.Fahrenheit()
+}
+
+// unitColumns returns cols with the columns replaced by units.
+func unitColumns(cols []column, units common.UnitSystem) []column {
+	if units != common.Imperial {
+		return cols
+	}
+
+	result := make([]column, len(cols))
+	for i, c := range cols {
+		if conv, ok := imperialConversions[c.Key]; ok {
+			c = conv.column
+		}
+		result[i] = c
+	}
+	return result
+}
+
+// convertRecord returns a copy of rec with its data and derived fields
+// converted to units.
+func convertRecord(rec *record, units common.UnitSystem) (*record, error) {
+	if units == common.Metric {
+		return rec, nil
+	}
+
+	converted := *rec
+	var err error
+	if converted.Data, err = convertUnits(rec.Data, units); err != nil {
+		return nil, err
+	}
+	if converted.Derived, err = convertUnits(rec.Derived, units); err != nil {
+		return nil, err
+	}
+	return &converted, nil
+}
+
+// convertUnits converts the fields of a JSON object to units, keeping their
+// order. Converted values are rounded to four decimal places.
+func convertUnits(obj json.RawMessage, units common.UnitSystem) (json.RawMessage, error) {
+	if units != common.Imperial || len(obj) == 0 {
+		return obj, nil
+	}
+
+	fields, err := orderedFields(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, f := range fields {
+		conv, ok := imperialConversions[f.Key]
+		if !ok {
+			continue
+		}
+		fields[i].Key = conv.column.Key
+
+		n, ok := f.Value.(json.Number)
+		if !ok {
+			continue // null when not available
+		}
+		v, err := n.Float64()
+		if err != nil {
+			return nil, err
+		}
+		v = math.Round(conv.convert(v)*1e4) / 1e4
+		fields[i].Value = json.Number(strconv.FormatFloat(v, 'f', -1, 64))
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSONValue(&buf, fields); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeJSONValue writes a value read by readJSONValue back as JSON.
+func writeJSONValue(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case []field:
+		buf.WriteByte('{')
+		for i, f := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := json.Marshal(f.Key)
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeJSONValue(buf, f.Value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []any:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONValue(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case nil, bool, string, json.Number:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	default:
+		return fmt.Errorf("unexpected JSON value %T", v)
+	}
+	return nil
+}
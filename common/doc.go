@@ -8,14 +8,24 @@
 //
 // The package provides structured types for common sensor measurements:
 //
-// - Temperature: measured in degrees Celsius
+// - Temperature: measured in degrees Celsius, converts to Fahrenheit and kelvins
 // - Humidity: measured in percent
-// - Pressure: measured in Pascals
-// - Acceleration: measured in G (gravitational force)
-// - BatteryVoltage: measured in millivolts
-// - TxPower: measured in dBm
+// - Pressure: measured in Pascals, converts to hPa, kPa, inHg and mmHg
+// - Acceleration: measured in G (gravitational force), converts to m/s²
+// - BatteryVoltage: measured in millivolts, converts to volts
+// - TxPower: measured in dBm, converts to milliwatts
 // - MACAddress: 48-bit device identifier
 //
+// The measurement types implement fmt.Stringer, and Format formats them in
+// the units of a UnitSystem: Metric (°C, hPa) or Imperial (°F, inHg).
+//
+// # Reception Metadata
+//
+// Envelope wraps decoded data with when and by which receiver an
+// advertisement was heard, the Bluetooth address and AddressType of the
+// sender, the RSSI and the raw payload. Unknown metadata is left zero or nil
+// and omitted from JSON.
+//
 // # Invalid Values
 //
 // Throughout the Ruuvi protocols, nil pointers are used to indicate
@@ -0,0 +1,92 @@
+package common
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Envelope wraps data decoded from a BLE advertisement with how, when and
+// where the advertisement was received. Fields other than Data are zero or
+// nil when not known, e.g. for payloads pasted on the command line.
+type Envelope[T any] struct {
+	ReceivedAt  time.Time   `json:"timestamp,omitzero"`    // When the advertisement was received
+	Source      string      `json:"source,omitempty"`      // ID of the gateway or receiver that heard it
+	Address     *MACAddress `json:"mac,omitempty"`         // BLE address of the sender
+	AddressType AddressType `json:"address_type,omitzero"` // Type of Address
+	RSSI        *int        `json:"rssi,omitempty"`        // Received signal strength in dBm
+	Raw         HexBytes    `json:"raw,omitempty"`         // Payload the data was decoded from
+	Data        T           `json:"data"`                  // Decoded data
+}
+
+// AddressType is the type of a Bluetooth LE device address.
+type AddressType uint8
+
+const (
+	AddressTypeUnknown                    AddressType = iota
+	AddressTypePublic                                 // IEEE-assigned public address
+	AddressTypeRandomStatic                           // Random address that is fixed until power cycle
+	AddressTypeRandomResolvablePrivate                // Random address resolvable with the identity key
+	AddressTypeRandomNonResolvablePrivate             // Random address that cannot be resolved
+)
+
+// addressTypeNames are the text forms of the address types, in order.
+var addressTypeNames = []string{"unknown", "public", "random_static", "random_resolvable", "random_non_resolvable"}
+
+// String returns the text form of the address type, e.g. "random_static".
+func (t AddressType) String() string {
+	if int(t) < len(addressTypeNames) {
+		return addressTypeNames[t]
+	}
+	return fmt.Sprintf("AddressType(%d)", uint8(t))
+}
+
+// MarshalText encodes the address type in its text form.
+func (t AddressType) MarshalText() ([]byte, error) {
+	if int(t) >= len(addressTypeNames) {
+		return nil, fmt.Errorf("invalid address type %d", uint8(t))
+	}
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText decodes an address type from its text form. "random" is
+// accepted for addresses whose kind of random address is not known and
+// decodes as AddressTypeRandomStatic, as used by RuuviTags.
+func (t *AddressType) UnmarshalText(text []byte) error {
+	s := strings.ToLower(string(text))
+	if s == "random" {
+		*t = AddressTypeRandomStatic
+		return nil
+	}
+	for i, name := range addressTypeNames {
+		if s == name {
+			*t = AddressType(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid address type %q", text)
+}
+
+// HexBytes is a byte slice encoded as upper-case hex in text and JSON.
+type HexBytes []byte
+
+// String returns the bytes as upper-case hex, e.g. "0512FC".
+func (b HexBytes) String() string {
+	return strings.ToUpper(hex.EncodeToString(b))
+}
+
+// MarshalText encodes the bytes as upper-case hex.
+func (b HexBytes) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText decodes hex in either case.
+func (b *HexBytes) UnmarshalText(text []byte) error {
+	decoded, err := hex.DecodeString(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid hex bytes: %w", err)
+	}
+	*b = decoded
+	return nil
+}
@@ -0,0 +1,87 @@
+package common
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEnvelope_JSON(t *testing.T) {
+	mac := MACAddress{0xCB, 0xB8, 0x33, 0x4C, 0x88, 0x4F}
+	rssi := -67
+	env := Envelope[map[string]int]{
+		ReceivedAt:  time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Source:      "gw-1",
+		Address:     &mac,
+		AddressType: AddressTypeRandomStatic,
+		RSSI:        &rssi,
+		Raw:         HexBytes{0x05, 0x12, 0xFC},
+		Data:        map[string]int{"x": 1},
+	}
+
+	b, err := json.Marshal(env)
+	if err != nil {
+		t.Fatalf("json.Marshal error: %v", err)
+	}
+	want := `{"timestamp":"2025-01-02T03:04:05Z","source":"gw-1","mac":"CB:B8:33:4C:88:4F",` +
+		`"address_type":"random_static","rssi":-67,"raw":"0512FC","data":{"x":1}}`
+	if string(b) != want {
+		t.Fatalf("json.Marshal = %s; want %s", b, want)
+	}
+
+	var got Envelope[map[string]int]
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("json.Unmarshal error: %v", err)
+	}
+	if !got.ReceivedAt.Equal(env.ReceivedAt) || *got.Address != mac || got.AddressType != env.AddressType ||
+		*got.RSSI != rssi || got.Raw.String() != "0512FC" || got.Data["x"] != 1 {
+		t.Fatalf("json.Unmarshal = %+v; want %+v", got, env)
+	}
+}
+
+func TestEnvelope_JSONOmitsUnknown(t *testing.T) {
+	b, err := json.Marshal(Envelope[int]{Data: 1})
+	if err != nil {
+		t.Fatalf("json.Marshal error: %v", err)
+	}
+	if string(b) != `{"data":1}` {
+		t.Fatalf("json.Marshal = %s; want {\"data\":1}", b)
+	}
+}
+
+func TestAddressType_Text(t *testing.T) {
+	for typ := AddressTypeUnknown; typ <= AddressTypeRandomNonResolvablePrivate; typ++ {
+		text, err := typ.MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText(%d) error: %v", typ, err)
+		}
+		var got AddressType
+		if err := got.UnmarshalText(text); err != nil || got != typ {
+			t.Errorf("UnmarshalText(%s) = %v, %v; want %v", text, got, err, typ)
+		}
+	}
+
+	var got AddressType
+	if err := got.UnmarshalText([]byte("Random")); err != nil || got != AddressTypeRandomStatic {
+		t.Errorf("UnmarshalText(Random) = %v, %v; want random_static", got, err)
+	}
+	if err := got.UnmarshalText([]byte("private")); err == nil {
+		t.Error("UnmarshalText(private) = nil error; want error")
+	}
+	if _, err := AddressType(9).MarshalText(); err == nil {
+		t.Error("MarshalText(9) = nil error; want error")
+	}
+	if s := AddressType(9).String(); s != "AddressType(9)" {
+		t.Errorf("String() = %q; want AddressType(9)", s)
+	}
+}
+
+func TestHexBytes_UnmarshalText(t *testing.T) {
+	var b HexBytes
+	if err := b.UnmarshalText([]byte("0512fc")); err != nil || b.String() != "0512FC" {
+		t.Errorf("UnmarshalText(0512fc) = %s, %v; want 0512FC", b, err)
+	}
+	if err := b.UnmarshalText([]byte("05Z")); err == nil {
+		t.Error("UnmarshalText(05Z) = nil error; want error")
+	}
+}
@@ -2,8 +2,11 @@
 package common
 
 import (
+	"encoding/hex"
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
 )
 
 // Temperature represents a temperature measurement in degrees Celsius.
@@ -12,18 +15,100 @@ type Temperature struct {
 	Celsius float64
 }
 
+// Fahrenheit returns the temperature in degrees Fahrenheit.
+func (t Temperature) Fahrenheit() float64 {
+	return t.Celsius*9/5 + 32
+}
+
+// Kelvin returns the temperature in kelvins.
+func (t Temperature) Kelvin() float64 {
+	return t.Celsius + 273.15
+}
+
+// String formats the temperature in degrees Celsius, e.g. "24.30 °C".
+func (t Temperature) String() string {
+	return t.Format(Metric)
+}
+
+// Format formats the temperature in degrees Celsius for Metric and degrees
+// Fahrenheit for Imperial, with two decimals.
+func (t Temperature) Format(u UnitSystem) string {
+	if u == Imperial {
+		return fmt.Sprintf("%.2f °F", t.Fahrenheit())
+	}
+	return fmt.Sprintf("%.2f °C", t.Celsius)
+}
+
 // Humidity represents a relative humidity measurement in percent.
 // A nil pointer indicates an invalid or unavailable reading.
 type Humidity struct {
 	Percent float64
 }
 
+// Fraction returns the relative humidity as a fraction between 0 and 1.
+func (h Humidity) Fraction() float64 {
+	return h.Percent / 100
+}
+
+// String formats the humidity in percent, e.g. "53.49 %".
+func (h Humidity) String() string {
+	return h.Format(Metric)
+}
+
+// Format formats the humidity in percent with two decimals. Relative
+// humidity has no imperial unit, so u is ignored.
+func (h Humidity) Format(u UnitSystem) string {
+	return fmt.Sprintf("%.2f %%", h.Percent)
+}
+
 // Pressure represents an atmospheric pressure measurement in Pascals.
 // A nil pointer indicates an invalid or unavailable reading.
 type Pressure struct {
 	Pascals int
 }
 
+// Pascals per unit of the pressure units Pressure converts to.
+const (
+	pascalsPerHectopascal         = 100
+	pascalsPerKilopascal          = 1000
+	pascalsPerInchOfMercury       = 3386.389
+	pascalsPerMillimeterOfMercury = 133.322387415
+)
+
+// Hectopascals returns the pressure in hectopascals, which equal millibars.
+func (p Pressure) Hectopascals() float64 {
+	return float64(p.Pascals) / pascalsPerHectopascal
+}
+
+// Kilopascals returns the pressure in kilopascals.
+func (p Pressure) Kilopascals() float64 {
+	return float64(p.Pascals) / pascalsPerKilopascal
+}
+
+// InchesOfMercury returns the pressure in inches of mercury (inHg).
+func (p Pressure) InchesOfMercury() float64 {
+	return float64(p.Pascals) / pascalsPerInchOfMercury
+}
+
+// MillimetersOfMercury returns the pressure in millimeters of mercury (mmHg).
+func (p Pressure) MillimetersOfMercury() float64 {
+	return float64(p.Pascals) / pascalsPerMillimeterOfMercury
+}
+
+// String formats the pressure in hectopascals, e.g. "1000.44 hPa".
+func (p Pressure) String() string {
+	return p.Format(Metric)
+}
+
+// Format formats the pressure in hectopascals for Metric and inches of
+// mercury for Imperial, with two decimals.
+func (p Pressure) Format(u UnitSystem) string {
+	if u == Imperial {
+		return fmt.Sprintf("%.2f inHg", p.InchesOfMercury())
+	}
+	return fmt.Sprintf("%.2f hPa", p.Hectopascals())
+}
+
 // Acceleration represents an acceleration measurement in G (gravitational force).
 // A nil pointer indicates an invalid or unavailable reading.
 type Acceleration struct {
@@ -32,30 +117,90 @@ type Acceleration struct {
 	Z float64
 }
 
+// StandardGravity is the acceleration of 1 G in m/s².
+const StandardGravity = 9.80665
+
+// MetersPerSecondSquared returns the acceleration along each axis in m/s².
+func (a Acceleration) MetersPerSecondSquared() (x, y, z float64) {
+	return a.X * StandardGravity, a.Y * StandardGravity, a.Z * StandardGravity
+}
+
+// Magnitude returns the length of the acceleration vector in G.
+func (a Acceleration) Magnitude() float64 {
+	return math.Sqrt(a.X*a.X + a.Y*a.Y + a.Z*a.Z)
+}
+
+// String formats the acceleration in G, e.g. "(0.004, -0.004, 1.036) g".
+func (a Acceleration) String() string {
+	return a.Format(Metric)
+}
+
+// Format formats the acceleration along each axis in G with three decimals
+// for Metric and Imperial alike.
+func (a Acceleration) Format(u UnitSystem) string {
+	return fmt.Sprintf("(%.3f, %.3f, %.3f) g", a.X, a.Y, a.Z)
+}
+
 // BatteryVoltage represents a battery voltage measurement in millivolts.
 // A nil pointer indicates an invalid or unavailable reading.
 type BatteryVoltage struct {
 	Millivolts int
 }
 
+// Volts returns the battery voltage in volts.
+func (v BatteryVoltage) Volts() float64 {
+	return float64(v.Millivolts) / 1000
+}
+
+// String formats the battery voltage in volts, e.g. "2.977 V".
+func (v BatteryVoltage) String() string {
+	return v.Format(Metric)
+}
+
+// Format formats the battery voltage in volts with three decimals for Metric
+// and Imperial alike.
+func (v BatteryVoltage) Format(u UnitSystem) string {
+	return fmt.Sprintf("%.3f V", v.Volts())
+}
+
 // TxPower represents transmit power in dBm.
 // A nil pointer indicates an invalid or unavailable reading.
 type TxPower struct {
 	DBm int
 }
 
+// Milliwatts returns the transmit power in milliwatts.
+func (p TxPower) Milliwatts() float64 {
+	return math.Pow(10, float64(p.DBm)/10)
+}
+
+// String formats the transmit power in dBm, e.g. "4 dBm".
+func (p TxPower) String() string {
+	return fmt.Sprintf("%d dBm", p.DBm)
+}
+
 // MovementCounter tracks movement detection events.
 // A nil pointer indicates an invalid or unavailable reading.
 type MovementCounter struct {
 	Count uint8
 }
 
+// String formats the movement counter as a plain number.
+func (c MovementCounter) String() string {
+	return strconv.Itoa(int(c.Count))
+}
+
 // MeasurementSequence tracks measurement sequence numbers for deduplication.
 // A nil pointer indicates an invalid or unavailable reading.
 type MeasurementSequence struct {
 	Number uint16
 }
 
+// String formats the sequence number as a plain number.
+func (s MeasurementSequence) String() string {
+	return strconv.Itoa(int(s.Number))
+}
+
 // MACAddress represents a 48-bit MAC address.
 // A nil pointer or all 0xFF bytes indicates an invalid or unavailable MAC.
 type MACAddress [6]byte
@@ -66,6 +211,42 @@ func (m MACAddress) String() string {
 		m[0], m[1], m[2], m[3], m[4], m[5])
 }
 
+// MarshalText encodes the MAC address in colon-separated hex format, so that
+// it appears as "AA:BB:CC:DD:EE:FF" in JSON and other text encodings.
+func (m MACAddress) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText decodes a MAC address in any notation accepted by ParseMACAddress.
+func (m *MACAddress) UnmarshalText(text []byte) error {
+	parsed, err := ParseMACAddress(string(text))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// ParseMACAddress parses a MAC address in colon-separated ("AA:BB:CC:DD:EE:FF"),
+// dash-separated ("AA-BB-CC-DD-EE-FF") or plain ("AABBCCDDEEFF") hex notation.
+// Hex digits are accepted in either case.
+func ParseMACAddress(s string) (MACAddress, error) {
+	var m MACAddress
+
+	clean := strings.NewReplacer(":", "", "-", "").Replace(s)
+	if len(clean) != 12 {
+		return m, fmt.Errorf("invalid MAC address %q: want 6 bytes", s)
+	}
+
+	b, err := hex.DecodeString(clean)
+	if err != nil {
+		return m, fmt.Errorf("invalid MAC address %q: %w", s, err)
+	}
+
+	copy(m[:], b)
+	return m, nil
+}
+
 // IsInvalid checks if the MAC address is invalid (all 0xFF).
 func (m MACAddress) IsInvalid() bool {
 	for _, b := range m {
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"encoding/json"
 	"math"
 	"testing"
 )
@@ -13,6 +14,49 @@ func TestMACString(t *testing.T) {
 	}
 }
 
+func TestParseMACAddress(t *testing.T) {
+	want := MACAddress{0xCB, 0xB8, 0x33, 0x4C, 0x88, 0x4F}
+	for _, s := range []string{"CB:B8:33:4C:88:4F", "cb-b8-33-4c-88-4f", "CBB8334C884F"} {
+		got, err := ParseMACAddress(s)
+		if err != nil {
+			t.Fatalf("ParseMACAddress(%q) error: %v", s, err)
+		}
+		if got != want {
+			t.Fatalf("ParseMACAddress(%q) = %v; want %v", s, got, want)
+		}
+	}
+
+	for _, s := range []string{"", "CB:B8:33:4C:88", "CB:B8:33:4C:88:4G", "CB:B8:33:4C:88:4F:00"} {
+		if _, err := ParseMACAddress(s); err == nil {
+			t.Fatalf("ParseMACAddress(%q) = nil error; want error", s)
+		}
+	}
+}
+
+func TestMACAddress_JSON(t *testing.T) {
+	mac := MACAddress{0xCB, 0xB8, 0x33, 0x4C, 0x88, 0x4F}
+
+	b, err := json.Marshal(&mac)
+	if err != nil {
+		t.Fatalf("json.Marshal error: %v", err)
+	}
+	if string(b) != `"CB:B8:33:4C:88:4F"` {
+		t.Fatalf("json.Marshal = %s; want \"CB:B8:33:4C:88:4F\"", b)
+	}
+
+	var got MACAddress
+	if err := json.Unmarshal([]byte(`"cb-b8-33-4c-88-4f"`), &got); err != nil {
+		t.Fatalf("json.Unmarshal error: %v", err)
+	}
+	if got != mac {
+		t.Fatalf("json.Unmarshal = %v; want %v", got, mac)
+	}
+
+	if err := json.Unmarshal([]byte(`[203,184,51,76,136,79]`), &got); err == nil {
+		t.Fatal("json.Unmarshal of byte array = nil error; want error")
+	}
+}
+
 func TestMACIsInvalid(t *testing.T) {
 	allFF := MACAddress{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
 	if !allFF.IsInvalid() {
@@ -0,0 +1,38 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnitSystem selects the units quantities are formatted in.
+type UnitSystem uint8
+
+const (
+	Metric   UnitSystem = iota // °C, hPa
+	Imperial                   // °F, inHg
+)
+
+// String returns the name of the unit system, "metric" or "imperial".
+func (u UnitSystem) String() string {
+	switch u {
+	case Metric:
+		return "metric"
+	case Imperial:
+		return "imperial"
+	default:
+		return fmt.Sprintf("UnitSystem(%d)", uint8(u))
+	}
+}
+
+// ParseUnitSystem parses "metric" or "imperial", in either case.
+func ParseUnitSystem(s string) (UnitSystem, error) {
+	switch strings.ToLower(s) {
+	case "metric":
+		return Metric, nil
+	case "imperial":
+		return Imperial, nil
+	default:
+		return Metric, fmt.Errorf("unknown unit system %q (supported: metric, imperial)", s)
+	}
+}
@@ -0,0 +1,109 @@
+package common
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTemperature_Conversions(t *testing.T) {
+	tests := []struct {
+		celsius, fahrenheit, kelvin float64
+	}{
+		{0, 32, 273.15},
+		{100, 212, 373.15},
+		{-40, -40, 233.15},
+		{24.3, 75.74, 297.45},
+	}
+
+	for _, tt := range tests {
+		temp := Temperature{Celsius: tt.celsius}
+		if got := temp.Fahrenheit(); math.Abs(got-tt.fahrenheit) > 1e-9 {
+			t.Errorf("Fahrenheit(%v) = %v; want %v", tt.celsius, got, tt.fahrenheit)
+		}
+		if got := temp.Kelvin(); math.Abs(got-tt.kelvin) > 1e-9 {
+			t.Errorf("Kelvin(%v) = %v; want %v", tt.celsius, got, tt.kelvin)
+		}
+	}
+}
+
+func TestPressure_Conversions(t *testing.T) {
+	p := Pressure{Pascals: 101325}
+
+	if got := p.Hectopascals(); got != 1013.25 {
+		t.Errorf("Hectopascals() = %v; want 1013.25", got)
+	}
+	if got := p.Kilopascals(); got != 101.325 {
+		t.Errorf("Kilopascals() = %v; want 101.325", got)
+	}
+	if got := p.InchesOfMercury(); math.Abs(got-29.9213) > 1e-4 {
+		t.Errorf("InchesOfMercury() = %v; want 29.9213", got)
+	}
+	if got := p.MillimetersOfMercury(); math.Abs(got-760) > 0.001 {
+		t.Errorf("MillimetersOfMercury() = %v; want 760", got)
+	}
+}
+
+func TestAcceleration_Conversions(t *testing.T) {
+	a := Acceleration{X: 0, Y: -0.5, Z: 1}
+
+	x, y, z := a.MetersPerSecondSquared()
+	if x != 0 || y != -StandardGravity/2 || z != StandardGravity {
+		t.Errorf("MetersPerSecondSquared() = %v, %v, %v", x, y, z)
+	}
+	if got := (Acceleration{X: 3, Y: 4}).Magnitude(); got != 5 {
+		t.Errorf("Magnitude() = %v; want 5", got)
+	}
+}
+
+func TestBatteryVoltageAndTxPower_Conversions(t *testing.T) {
+	if got := (BatteryVoltage{Millivolts: 2977}).Volts(); got != 2.977 {
+		t.Errorf("Volts() = %v; want 2.977", got)
+	}
+	if got := (TxPower{DBm: 0}).Milliwatts(); got != 1 {
+		t.Errorf("Milliwatts(0 dBm) = %v; want 1", got)
+	}
+	if got := (TxPower{DBm: -20}).Milliwatts(); math.Abs(got-0.01) > 1e-12 {
+		t.Errorf("Milliwatts(-20 dBm) = %v; want 0.01", got)
+	}
+}
+
+func TestQuantities_Format(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"temperature", Temperature{Celsius: 24.3}.String(), "24.30 °C"},
+		{"temperature imperial", Temperature{Celsius: 24.3}.Format(Imperial), "75.74 °F"},
+		{"humidity", Humidity{Percent: 53.49}.Format(Imperial), "53.49 %"},
+		{"pressure", Pressure{Pascals: 100044}.String(), "1000.44 hPa"},
+		{"pressure imperial", Pressure{Pascals: 100044}.Format(Imperial), "29.54 inHg"},
+		{"acceleration", Acceleration{X: 0.004, Y: -0.004, Z: 1.036}.String(), "(0.004, -0.004, 1.036) g"},
+		{"battery voltage", BatteryVoltage{Millivolts: 2977}.String(), "2.977 V"},
+		{"TX power", TxPower{DBm: 4}.String(), "4 dBm"},
+		{"movement counter", MovementCounter{Count: 66}.String(), "66"},
+		{"measurement sequence", MeasurementSequence{Number: 205}.String(), "205"},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q; want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestParseUnitSystem(t *testing.T) {
+	for _, u := range []UnitSystem{Metric, Imperial} {
+		got, err := ParseUnitSystem(u.String())
+		if err != nil || got != u {
+			t.Errorf("ParseUnitSystem(%q) = %v, %v; want %v", u.String(), got, err, u)
+		}
+	}
+
+	if got, err := ParseUnitSystem("Imperial"); err != nil || got != Imperial {
+		t.Errorf("ParseUnitSystem(Imperial) = %v, %v; want imperial", got, err)
+	}
+	if _, err := ParseUnitSystem("nautical"); err == nil {
+		t.Error("ParseUnitSystem(nautical) = nil error; want error")
+	}
+}
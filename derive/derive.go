@@ -0,0 +1,173 @@
+// Package derive computes derived environmental metrics, such as dew point and
+// air density, from RuuviTag temperature, humidity and pressure readings.
+//
+// The scalar functions take plain values in the units used throughout this
+// module (degrees Celsius, percent relative humidity, Pascals). Compute takes
+// a normalized tag.Measurement of any data format and leaves a metric nil when
+// one of its inputs is unavailable.
+package derive
+
+import (
+	"math"
+
+	"github.com/marcgeld/ruuvi/tag"
+)
+
+// Physical constants used by the formulas in this package.
+const (
+	// StandardSeaLevelPressure is the ICAO standard atmosphere pressure at sea level in Pa.
+	StandardSeaLevelPressure = 101325.0
+
+	// gasConstantDryAir is the specific gas constant of dry air in J/(kg·K).
+	gasConstantDryAir = 287.05
+
+	// gasConstantWaterVapor is the specific gas constant of water vapor in J/(kg·K).
+	gasConstantWaterVapor = 461.5
+
+	// zeroCelsius is 0 °C in Kelvin.
+	zeroCelsius = 273.15
+)
+
+// Magnus formula coefficients over water (Sonntag 1990), valid from -45 °C to 60 °C.
+const (
+	magnusA = 611.2  // Pa
+	magnusB = 17.62  // dimensionless
+	magnusC = 243.12 // °C
+)
+
+// Metrics holds metrics derived from a single measurement.
+// A nil field indicates that an input of the metric is unavailable.
+type Metrics struct {
+	EquilibriumVaporPressure *float64 `json:"equilibrium_vapor_pressure_pa"` // Saturation vapor pressure in Pa
+	DewPoint                 *float64 `json:"dew_point_c"`                   // Dew point in degrees Celsius
+	AbsoluteHumidity         *float64 `json:"absolute_humidity_g_m3"`        // Absolute humidity in g/m³
+	VaporPressureDeficit     *float64 `json:"vapor_pressure_deficit_pa"`     // Vapor pressure deficit in Pa
+	AirDensity               *float64 `json:"air_density_kg_m3"`             // Density of moist air in kg/m³
+	HeatIndex                *float64 `json:"heat_index_c"`                  // Apparent temperature in degrees Celsius
+	Altitude                 *float64 `json:"altitude_m"`                    // Barometric altitude above sea level in meters
+}
+
+// Compute derives all metrics from a measurement of any data format.
+// Altitude is relative to StandardSeaLevelPressure. Returns empty Metrics
+// if m is nil.
+func Compute(m *tag.Measurement) *Metrics {
+	result := &Metrics{}
+	if m == nil {
+		return result
+	}
+
+	var pressure *float64
+	if m.Pressure != nil {
+		p := float64(*m.Pressure)
+		pressure = &p
+	}
+
+	if t := m.Temperature; t != nil {
+		result.EquilibriumVaporPressure = ptr(EquilibriumVaporPressure(*t))
+
+		if rh := m.Humidity; rh != nil {
+			if *rh > 0 {
+				result.DewPoint = ptr(DewPoint(*t, *rh))
+			}
+			result.AbsoluteHumidity = ptr(AbsoluteHumidity(*t, *rh))
+			result.VaporPressureDeficit = ptr(VaporPressureDeficit(*t, *rh))
+			result.HeatIndex = ptr(HeatIndex(*t, *rh))
+
+			if pressure != nil {
+				result.AirDensity = ptr(AirDensity(*t, *rh, *pressure))
+			}
+		}
+	}
+
+	if pressure != nil && *pressure > 0 {
+		result.Altitude = ptr(BarometricAltitude(*pressure, StandardSeaLevelPressure))
+	}
+
+	return result
+}
+
+// EquilibriumVaporPressure returns the saturation vapor pressure of water in Pa
+// at temperature t in degrees Celsius, using the Magnus formula.
+func EquilibriumVaporPressure(t float64) float64 {
+	return magnusA * math.Exp(magnusB*t/(magnusC+t))
+}
+
+// VaporPressure returns the partial pressure of water vapor in Pa at
+// temperature t in degrees Celsius and relative humidity rh in percent.
+func VaporPressure(t, rh float64) float64 {
+	return rh / 100 * EquilibriumVaporPressure(t)
+}
+
+// DewPoint returns the dew point in degrees Celsius at temperature t in
+// degrees Celsius and relative humidity rh in percent. Returns NaN if rh is
+// not positive.
+func DewPoint(t, rh float64) float64 {
+	if rh <= 0 {
+		return math.NaN()
+	}
+	gamma := math.Log(rh/100) + magnusB*t/(magnusC+t)
+	return magnusC * gamma / (magnusB - gamma)
+}
+
+// AbsoluteHumidity returns the mass of water vapor per volume of air in g/m³
+// at temperature t in degrees Celsius and relative humidity rh in percent.
+func AbsoluteHumidity(t, rh float64) float64 {
+	return VaporPressure(t, rh) / (gasConstantWaterVapor * (t + zeroCelsius)) * 1000
+}
+
+// VaporPressureDeficit returns the difference between the saturation and the
+// actual vapor pressure in Pa at temperature t in degrees Celsius and relative
+// humidity rh in percent.
+func VaporPressureDeficit(t, rh float64) float64 {
+	return EquilibriumVaporPressure(t) - VaporPressure(t, rh)
+}
+
+// AirDensity returns the density of moist air in kg/m³ at temperature t in
+// degrees Celsius, relative humidity rh in percent and pressure p in Pa.
+func AirDensity(t, rh, p float64) float64 {
+	kelvin := t + zeroCelsius
+	pv := VaporPressure(t, rh)
+	return (p-pv)/(gasConstantDryAir*kelvin) + pv/(gasConstantWaterVapor*kelvin)
+}
+
+// HeatIndex returns the apparent temperature in degrees Celsius at temperature
+// t in degrees Celsius and relative humidity rh in percent, using the US
+// National Weather Service algorithm (Rothfusz regression with adjustments).
+// Below about 27 °C the heat index is close to the air temperature.
+func HeatIndex(t, rh float64) float64 {
+	f := t*9/5 + 32
+
+	// Simple formula, accurate when the heat index is below 80 °F
+	hi := 0.5 * (f + 61 + (f-68)*1.2 + rh*0.094)
+
+	if (hi+f)/2 >= 80 {
+		hi = -42.379 + 2.04901523*f + 10.14333127*rh -
+			0.22475541*f*rh - 0.00683783*f*f - 0.05481717*rh*rh +
+			0.00122874*f*f*rh + 0.00085282*f*rh*rh - 0.00000199*f*f*rh*rh
+
+		switch {
+		case rh < 13 && f >= 80 && f <= 112:
+			hi -= (13 - rh) / 4 * math.Sqrt((17-math.Abs(f-95))/17)
+		case rh > 85 && f >= 80 && f <= 87:
+			hi += (rh - 85) / 10 * (87 - f) / 5
+		}
+	}
+
+	return (hi - 32) * 5 / 9
+}
+
+// BarometricAltitude returns the altitude in meters at pressure p in Pa,
+// given the pressure p0 at sea level in Pa, using the international barometric
+// formula. Pass StandardSeaLevelPressure for p0 when the local sea level
+// pressure is unknown.
+func BarometricAltitude(p, p0 float64) float64 {
+	return 44330 * (1 - math.Pow(p/p0, 1/5.255))
+}
+
+// ptr returns a pointer to v, or nil if v is NaN or infinite.
+func ptr(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
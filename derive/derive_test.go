@@ -0,0 +1,147 @@
+package derive
+
+import (
+	"encoding/json"
+	"maps"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/marcgeld/ruuvi/tag"
+)
+
+func almostEqual(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestScalarFunctions(t *testing.T) {
+	tests := []struct {
+		name      string
+		got       float64
+		want      float64
+		tolerance float64
+	}{
+		{name: "equilibrium vapor pressure", got: EquilibriumVaporPressure(20), want: 2332.596, tolerance: 0.001},
+		{name: "vapor pressure", got: VaporPressure(20, 50), want: 1166.298, tolerance: 0.001},
+		{name: "dew point", got: DewPoint(20, 50), want: 9.2552, tolerance: 0.0001},
+		{name: "dew point at saturation", got: DewPoint(15, 100), want: 15, tolerance: 1e-9},
+		{name: "absolute humidity", got: AbsoluteHumidity(20, 50), want: 8.6208, tolerance: 0.0001},
+		{name: "vapor pressure deficit", got: VaporPressureDeficit(20, 50), want: 1166.298, tolerance: 0.001},
+		{name: "air density", got: AirDensity(20, 50, 101325), want: 1.19888, tolerance: 0.00001},
+		{name: "heat index hot", got: HeatIndex(32, 70), want: 40.409, tolerance: 0.001},
+		{name: "heat index mild", got: HeatIndex(20, 50), want: 19.361, tolerance: 0.001},
+		{name: "altitude at sea level", got: BarometricAltitude(StandardSeaLevelPressure, StandardSeaLevelPressure), want: 0, tolerance: 1e-9},
+		{name: "altitude", got: BarometricAltitude(90000, StandardSeaLevelPressure), want: 988.647, tolerance: 0.001},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !almostEqual(tt.got, tt.want, tt.tolerance) {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+
+	if !math.IsNaN(DewPoint(20, 0)) {
+		t.Error("DewPoint(20, 0) should be NaN")
+	}
+}
+
+func TestCompute(t *testing.T) {
+	temp, hum := 20.0, 50.0
+	pressure := uint32(101325)
+
+	m := Compute(&tag.Measurement{Temperature: &temp, Humidity: &hum, Pressure: &pressure})
+
+	for name, v := range map[string]*float64{
+		"EquilibriumVaporPressure": m.EquilibriumVaporPressure,
+		"DewPoint":                 m.DewPoint,
+		"AbsoluteHumidity":         m.AbsoluteHumidity,
+		"VaporPressureDeficit":     m.VaporPressureDeficit,
+		"AirDensity":               m.AirDensity,
+		"HeatIndex":                m.HeatIndex,
+		"Altitude":                 m.Altitude,
+	} {
+		if v == nil {
+			t.Errorf("%s = nil, want value", name)
+		}
+	}
+
+	if !almostEqual(*m.DewPoint, 9.2552, 0.0001) {
+		t.Errorf("DewPoint = %v, want 9.2552", *m.DewPoint)
+	}
+	if !almostEqual(*m.Altitude, 0, 1e-9) {
+		t.Errorf("Altitude = %v, want 0", *m.Altitude)
+	}
+}
+
+func TestCompute_NilPropagation(t *testing.T) {
+	temp, zero := 20.0, 0.0
+	pressure := uint32(90000)
+
+	tests := []struct {
+		name string
+		in   *tag.Measurement
+		want map[string]bool // metric name -> expected non-nil
+	}{
+		{name: "nil measurement", in: nil, want: map[string]bool{}},
+		{name: "temperature only", in: &tag.Measurement{Temperature: &temp}, want: map[string]bool{"evp": true}},
+		{name: "pressure only", in: &tag.Measurement{Pressure: &pressure}, want: map[string]bool{"altitude": true}},
+		{
+			name: "no humidity",
+			in:   &tag.Measurement{Temperature: &temp, Pressure: &pressure},
+			want: map[string]bool{"evp": true, "altitude": true},
+		},
+		{
+			name: "zero humidity",
+			in:   &tag.Measurement{Temperature: &temp, Humidity: &zero},
+			want: map[string]bool{"evp": true, "absolute": true, "vpd": true, "heat": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Compute(tt.in)
+			got := map[string]*float64{
+				"evp":      m.EquilibriumVaporPressure,
+				"dew":      m.DewPoint,
+				"absolute": m.AbsoluteHumidity,
+				"vpd":      m.VaporPressureDeficit,
+				"density":  m.AirDensity,
+				"heat":     m.HeatIndex,
+				"altitude": m.Altitude,
+			}
+			for name, v := range got {
+				if (v != nil) != tt.want[name] {
+					t.Errorf("%s non-nil = %v, want %v", name, v != nil, tt.want[name])
+				}
+			}
+		})
+	}
+}
+
+func TestMetrics_MatchesJSONSchema(t *testing.T) {
+	var schema struct {
+		Defs map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(tag.JSONSchema(), &schema); err != nil {
+		t.Fatalf("invalid JSON schema: %v", err)
+	}
+
+	b, err := json.Marshal(Metrics{})
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+
+	got := slices.Sorted(maps.Keys(fields))
+	want := slices.Sorted(maps.Keys(schema.Defs["derived"].Properties))
+	if !slices.Equal(got, want) {
+		t.Errorf("encoded keys %v, schema properties %v", got, want)
+	}
+}
@@ -0,0 +1,263 @@
+// Package influx exports decoded RuuviTag readings to InfluxDB.
+//
+// Encoder renders a tag.Envelope as a line of InfluxDB line protocol, and
+// Writer sends batches of lines to the InfluxDB v2 write API:
+//
+//	ruuvi,format=5,gateway=gw-1,mac=CB:B8:33:4C:88:4F temperature_c=24.3,pressure_pa=100044i,... 1735787045000000000
+//
+// Field keys are the JSON keys of the decoded data, so they carry the unit
+// of the value. Values are written with the type of the field in every
+// reading, so that a temperature of exactly 24 °C does not conflict with the
+// float field type established by earlier readings.
+package influx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/marcgeld/ruuvi/derive"
+	"github.com/marcgeld/ruuvi/tag"
+)
+
+// DefaultMeasurement is the measurement name used when Encoder.Measurement
+// is empty.
+const DefaultMeasurement = "ruuvi"
+
+// ErrNoFields is returned for envelopes without any available value, which
+// line protocol cannot represent.
+var ErrNoFields = errors.New("influx: no field values")
+
+// Encoder renders envelopes as InfluxDB line protocol. The zero value is
+// ready to use.
+//
+// Every line is tagged with the format of the data, the MAC address of the
+// tag returned by tag.EnvelopeMAC and the Source of the envelope as gateway,
+// where known. The sensor values are written as fields; values that are not
+// available are left out. The RSSI is written as the rssi_dbm field. The
+// timestamp is the ReceivedAt time of the envelope in nanoseconds, or left
+// for the server to assign if zero.
+type Encoder struct {
+	Measurement string            // Measurement name, DefaultMeasurement if empty
+	Tags        map[string]string // Tags added to every line, e.g. location
+	Derived     bool              // Add the derived metrics of package derive as fields
+}
+
+// Append appends the line protocol encoding of env to dst, terminated by a
+// newline. Returns ErrNoFields if env carries no available value.
+func (e *Encoder) Append(dst []byte, env *tag.Envelope) ([]byte, error) {
+	if env == nil || env.Data == nil {
+		return dst, ErrNoFields
+	}
+
+	fields, err := lineFields(env, e.Derived)
+	if err != nil {
+		return dst, err
+	}
+	if len(fields) == 0 {
+		return dst, ErrNoFields
+	}
+
+	measurement := e.Measurement
+	if measurement == "" {
+		measurement = DefaultMeasurement
+	}
+	dst = appendEscaped(dst, measurement, ", ")
+
+	for _, t := range e.lineTags(env) {
+		dst = append(dst, ',')
+		dst = appendEscaped(dst, t.key, ",= ")
+		dst = append(dst, '=')
+		dst = appendEscaped(dst, t.value, ",= ")
+	}
+
+	for i, f := range fields {
+		if i == 0 {
+			dst = append(dst, ' ')
+		} else {
+			dst = append(dst, ',')
+		}
+		dst = appendEscaped(dst, f.key, ",= ")
+		dst = append(dst, '=')
+		dst = append(dst, f.value...)
+	}
+
+	if !env.ReceivedAt.IsZero() {
+		dst = append(dst, ' ')
+		dst = strconv.AppendInt(dst, env.ReceivedAt.UnixNano(), 10)
+	}
+
+	return append(dst, '\n'), nil
+}
+
+// lineTag is a tag key and value, unescaped.
+type lineTag struct {
+	key, value string
+}
+
+// lineTags returns the tags of the line of env, sorted by key as
+// recommended for write performance. Tags with empty values are left out.
+func (e *Encoder) lineTags(env *tag.Envelope) []lineTag {
+	tags := make([]lineTag, 0, len(e.Tags)+3)
+	for k, v := range e.Tags {
+		if k != "" && v != "" {
+			tags = append(tags, lineTag{k, v})
+		}
+	}
+
+	tags = append(tags, lineTag{"format", env.Data.Format.String()})
+	if mac, ok := tag.EnvelopeMAC(env); ok {
+		tags = append(tags, lineTag{"mac", mac.String()})
+	}
+	if env.Source != "" {
+		tags = append(tags, lineTag{"gateway", env.Source})
+	}
+
+	slices.SortFunc(tags, func(a, b lineTag) int { return strings.Compare(a.key, b.key) })
+	return tags
+}
+
+// lineField is a field key, unescaped, and its encoded value.
+type lineField struct {
+	key   string
+	value []byte
+}
+
+// lineFields returns the available values of env as fields: the fields of
+// the data in payload order, custom fields in key order, the RSSI and the
+// derived metrics.
+func lineFields(env *tag.Envelope, derived bool) ([]lineField, error) {
+	values, err := jsonObject(env.Data)
+	if err != nil {
+		return nil, err
+	}
+	data, _ := values["data"].(map[string]any)
+
+	var fields []lineField
+	integers := make(map[string]bool)
+	for _, f := range tag.Fields(env.Data.Format) {
+		integers[f.Name] = isInteger(f)
+		if v, ok := data[f.Name]; ok {
+			fields = appendField(fields, f.Name, v, integers[f.Name])
+			delete(data, f.Name)
+		}
+	}
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		fields = appendField(fields, k, data[k], integers[k])
+	}
+
+	if env.RSSI != nil {
+		fields = append(fields, lineField{"rssi_dbm", append(strconv.AppendInt(nil, int64(*env.RSSI), 10), 'i')})
+	}
+
+	if derived {
+		metrics, err := jsonObject(derive.Compute(env.Data.Measurement()))
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]string, 0, len(metrics))
+		for k := range metrics {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			fields = appendField(fields, k, metrics[k], false)
+		}
+	}
+
+	return fields, nil
+}
+
+// appendField appends the field key=v, unless v is null. The MAC address is
+// left out, as it is written as a tag.
+func appendField(fields []lineField, key string, v any, integer bool) []lineField {
+	var value []byte
+	switch v := v.(type) {
+	case json.Number:
+		if integer {
+			if n, err := v.Int64(); err == nil {
+				value = append(strconv.AppendInt(nil, n, 10), 'i')
+				break
+			}
+		}
+		f, err := v.Float64()
+		if err != nil || math.IsInf(f, 0) {
+			return fields
+		}
+		value = strconv.AppendFloat(nil, f, 'g', -1, 64)
+	case string:
+		if key == "mac_address" {
+			return fields
+		}
+		value = appendQuoted(nil, v)
+	case bool:
+		value = strconv.AppendBool(nil, v)
+	default:
+		return fields // null, or nested values of custom formats
+	}
+	return append(fields, lineField{key, value})
+}
+
+// isInteger reports whether the values of f are whole numbers: counters,
+// flags and linear fields with a whole scale and offset.
+func isInteger(f tag.Field) bool {
+	switch f.Kind {
+	case tag.FieldFlags:
+		return true
+	case tag.FieldLinear:
+		return f.Scale == math.Trunc(f.Scale) && f.ValueOffset == math.Trunc(f.ValueOffset)
+	default:
+		return false
+	}
+}
+
+// jsonObject returns the JSON encoding of v as a map, with numbers as
+// json.Number.
+func jsonObject(v any) (map[string]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("influx: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("influx: %w", err)
+	}
+	return m, nil
+}
+
+// appendEscaped appends s with the characters in special escaped by a
+// backslash. Newlines, which end a line and cannot be escaped, are dropped.
+func appendEscaped(dst []byte, s, special string) []byte {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\n' {
+			continue
+		}
+		if strings.IndexByte(special, c) >= 0 {
+			dst = append(dst, '\\')
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
+
+// appendQuoted appends s as a double-quoted string field value.
+func appendQuoted(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	dst = appendEscaped(dst, s, `"\`)
+	return append(dst, '"')
+}
@@ -0,0 +1,144 @@
+package influx
+
+import (
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcgeld/ruuvi/common"
+	"github.com/marcgeld/ruuvi/tag"
+)
+
+func testEnvelope(t *testing.T, payload string) *tag.Envelope {
+	t.Helper()
+	data, err := hex.DecodeString(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := tag.NewEnvelope(data)
+	if err != nil {
+		t.Fatalf("NewEnvelope() error = %v", err)
+	}
+	return env
+}
+
+func TestEncoder_Append(t *testing.T) {
+	env := testEnvelope(t, "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F")
+	env.ReceivedAt = time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC)
+	env.Source = "gw-1"
+	rssi := -67
+	env.RSSI = &rssi
+
+	var e Encoder
+	got, err := e.Append(nil, env)
+	if err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	want := "ruuvi,format=5,gateway=gw-1,mac=CB:B8:33:4C:88:4F " +
+		"temperature_c=24.3,humidity_percent=53.49,pressure_pa=100044i," +
+		"acceleration_x_g=0.004,acceleration_y_g=-0.004,acceleration_z_g=1.036," +
+		"battery_voltage_mv=2977i,tx_power_dbm=4i,movement_counter=66i,measurement_sequence=205i," +
+		"rssi_dbm=-67i 1735787045000000006\n"
+	if string(got) != want {
+		t.Errorf("Append() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestEncoder_WholeFloatsStayFloats(t *testing.T) {
+	temp, pressure := 24.0, 100000
+	env := &tag.Envelope{Data: &tag.DecodedData{Format: tag.Format5, Format5: &tag.Format5Data{Temperature: &temp, Pressure: &pressure}}}
+
+	got, err := (&Encoder{}).Append(nil, env)
+	if err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if want := "ruuvi,format=5 temperature_c=24,pressure_pa=100000i\n"; string(got) != want {
+		t.Errorf("Append() = %q, want %q", got, want)
+	}
+}
+
+func TestEncoder_Options(t *testing.T) {
+	env := testEnvelope(t, "06170C5668C79E007000C90501D9FFCD004C884F")
+	mac := common.MACAddress{0xCB, 0xB8, 0x33, 0x4C, 0x88, 0x4F}
+	env.Address = &mac
+
+	e := Encoder{Measurement: "air quality", Tags: map[string]string{"room": "living room", "a=b": "c,d"}, Derived: true}
+	got, err := e.Append([]byte("previous\n"), env)
+	if err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	line := strings.TrimPrefix(string(got), "previous\n")
+	if !strings.HasPrefix(line, `air\ quality,a\=b=c\,d,format=6,mac=CB:B8:33:4C:88:4F,room=living\ room temperature_c=29.5,`) {
+		t.Errorf("unexpected line: %s", line)
+	}
+	for _, want := range []string{",co2_ppm=201i,", ",voc_index=10i,", ",flags=0i,", `,mac_suffix="4C:88:4F",`, ",dew_point_c="} {
+		if !strings.Contains(line, want) {
+			t.Errorf("expected %q in line: %s", want, line)
+		}
+	}
+	if strings.Count(line, " ")-strings.Count(line, `\ `) != 1 || !strings.HasSuffix(line, "\n") {
+		t.Errorf("expected no timestamp: %q", line)
+	}
+}
+
+func TestEncoder_NoFields(t *testing.T) {
+	// Format 5 with all values set to "not available"
+	env := testEnvelope(t, "058000FFFFFFFF800080008000FFFFFFFFFFFFFFFFFFFFFF")
+
+	var e Encoder
+	dst := []byte("kept\n")
+	got, err := e.Append(dst, env)
+	if !errors.Is(err, ErrNoFields) || string(got) != "kept\n" {
+		t.Errorf("Append() = %q, %v; want dst unchanged and ErrNoFields", got, err)
+	}
+	if _, err := e.Append(nil, nil); !errors.Is(err, ErrNoFields) {
+		t.Errorf("Append(nil) error = %v, want ErrNoFields", err)
+	}
+}
+
+// TestIsInteger checks that the field types derived from the layouts match
+// the Go types of the format structs.
+func TestIsInteger(t *testing.T) {
+	structs := map[tag.DataFormat]any{
+		tag.Format2: tag.Format2Data{}, tag.Format3: tag.Format3Data{}, tag.Format4: tag.Format4Data{},
+		tag.Format5: tag.Format5Data{}, tag.Format6: tag.Format6Data{}, tag.Format8: tag.Format8Data{},
+		tag.FormatC5: tag.FormatC5Data{}, tag.FormatE1: tag.FormatE1Data{},
+	}
+
+	for format, v := range structs {
+		kinds := make(map[string]reflect.Kind)
+		typ := reflect.TypeOf(v)
+		for i := range typ.NumField() {
+			f := typ.Field(i)
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			kinds[strings.Split(f.Tag.Get("json"), ",")[0]] = ft.Kind()
+		}
+
+		for _, f := range tag.Fields(format) {
+			kind, ok := kinds[f.Name]
+			if !ok || kind == reflect.Array || kind == reflect.String {
+				continue
+			}
+			if want := kind != reflect.Float64; isInteger(f) != want {
+				t.Errorf("format %s field %s: isInteger = %v, Go kind %s", format, f.Name, isInteger(f), kind)
+			}
+		}
+	}
+}
+
+func TestAppendEscaped(t *testing.T) {
+	if got := string(appendEscaped(nil, "a b,c=d\\e\nf", ",= ")); got != `a\ b\,c\=d\ef` {
+		t.Errorf("appendEscaped() = %q", got)
+	}
+	if got := string(appendQuoted(nil, `say "hi" \o/`)); got != `"say \"hi\" \\o/"` {
+		t.Errorf("appendQuoted() = %q", got)
+	}
+}
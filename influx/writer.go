@@ -0,0 +1,250 @@
+package influx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marcgeld/ruuvi/tag"
+)
+
+// Defaults of the Config fields left zero.
+const (
+	DefaultBatchSize     = 1000
+	DefaultMaxRetries    = 3
+	DefaultRetryInterval = time.Second
+)
+
+// maxErrorBody is the longest error response body kept in a WriteError.
+const maxErrorBody = 1024
+
+// Config configures a Writer.
+type Config struct {
+	URL    string // Base URL of the server, e.g. "http://localhost:8086"
+	Org    string // Organization name or ID
+	Bucket string // Bucket name or ID
+	Token  string // API token, sent as "Authorization: Token ..."
+
+	// BatchSize is the number of lines buffered by Add before they are sent,
+	// DefaultBatchSize if 0.
+	BatchSize int
+
+	// MaxRetries is the number of retries of a failed request after the
+	// first attempt, DefaultMaxRetries if 0. Negative disables retries.
+	MaxRetries int
+
+	// RetryInterval is the delay before the first retry, doubled for each
+	// further retry, DefaultRetryInterval if 0. A Retry-After header in the
+	// response takes precedence.
+	RetryInterval time.Duration
+
+	Encoder    Encoder      // Renders envelopes added with Add
+	HTTPClient *http.Client // http.DefaultClient if nil
+}
+
+// WriteError is returned when the server rejects a write.
+type WriteError struct {
+	StatusCode int    // HTTP status code
+	Message    string // Response body, truncated
+}
+
+func (e *WriteError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("influx: write failed: %s", http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("influx: write failed: %s: %s", http.StatusText(e.StatusCode), e.Message)
+}
+
+// temporary reports whether the write may succeed when retried.
+func (e *WriteError) temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// Writer sends line protocol to the write endpoint of the InfluxDB v2 API,
+// /api/v2/write, with nanosecond precision. Lines are buffered by Add and
+// sent in batches; requests that fail with a network error, 429 Too Many
+// Requests or a 5xx status are retried with exponential backoff.
+//
+// A Writer is safe for concurrent use. Batches are sent without blocking
+// Add, so batches sent by concurrent calls may arrive out of order.
+type Writer struct {
+	cfg      Config
+	endpoint string
+
+	mu    sync.Mutex
+	buf   []byte
+	lines int
+}
+
+// NewWriter returns a Writer for cfg. Returns an error if the URL, org or
+// bucket is missing or the URL is invalid.
+func NewWriter(cfg Config) (*Writer, error) {
+	if cfg.URL == "" || cfg.Org == "" || cfg.Bucket == "" {
+		return nil, errors.New("influx: URL, org and bucket are required")
+	}
+
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("influx: invalid URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("influx: invalid URL %q: want http or https", cfg.URL)
+	}
+	u = u.JoinPath("api", "v2", "write")
+	u.RawQuery = url.Values{"org": {cfg.Org}, "bucket": {cfg.Bucket}, "precision": {"ns"}}.Encode()
+
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = DefaultMaxRetries
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = DefaultRetryInterval
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+
+	return &Writer{cfg: cfg, endpoint: u.String()}, nil
+}
+
+// Add encodes env and buffers the line, sending the buffered lines once
+// BatchSize are buffered. Returns ErrNoFields for envelopes without any
+// available value, or the error of sending the batch.
+func (w *Writer) Add(ctx context.Context, env *tag.Envelope) error {
+	w.mu.Lock()
+	buf, err := w.cfg.Encoder.Append(w.buf, env)
+	if err != nil {
+		w.mu.Unlock()
+		return err
+	}
+	w.buf = buf
+	w.lines++
+
+	if w.lines < w.cfg.BatchSize {
+		w.mu.Unlock()
+		return nil
+	}
+	batch, lines := w.take()
+	w.mu.Unlock()
+
+	return w.send(ctx, batch, lines)
+}
+
+// Flush sends the buffered lines. The lines are dropped if sending fails
+// after all retries, so that a server outage does not grow the buffer
+// without bound.
+func (w *Writer) Flush(ctx context.Context) error {
+	w.mu.Lock()
+	batch, lines := w.take()
+	w.mu.Unlock()
+
+	return w.send(ctx, batch, lines)
+}
+
+// take removes the buffered lines from the Writer and returns them, so that
+// they can be sent without holding w.mu while Add keeps buffering. The
+// caller must hold w.mu.
+func (w *Writer) take() (batch []byte, lines int) {
+	batch, lines = w.buf, w.lines
+	if lines > 0 {
+		w.buf = make([]byte, 0, cap(batch))
+		w.lines = 0
+	}
+	return batch, lines
+}
+
+// send sends a batch removed by take.
+func (w *Writer) send(ctx context.Context, batch []byte, lines int) error {
+	if lines == 0 {
+		return nil
+	}
+
+	if err := w.Write(ctx, batch); err != nil {
+		return fmt.Errorf("%w (%d lines dropped)", err, lines)
+	}
+	return nil
+}
+
+// Write sends newline-separated lines of line protocol in one request,
+// retrying as described for Writer. It does not touch the lines buffered by
+// Add.
+func (w *Writer) Write(ctx context.Context, lines []byte) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retryAfter time.Duration
+		retryAfter, err = w.post(ctx, lines)
+		if err == nil || attempt >= w.cfg.MaxRetries || !retryable(err) {
+			return err
+		}
+
+		delay := w.cfg.RetryInterval << attempt
+		if retryAfter > 0 {
+			delay = retryAfter
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// post sends one request. On failure, it also returns the delay requested by
+// a Retry-After header in seconds, if any.
+func (w *Writer) post(ctx context.Context, lines []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.endpoint, bytes.NewReader(lines))
+	if err != nil {
+		return 0, fmt.Errorf("influx: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.cfg.Token != "" {
+		req.Header.Set("Authorization", "Token "+w.cfg.Token)
+	}
+
+	resp, err := w.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("influx: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if resp.StatusCode/100 == 2 {
+		return 0, nil
+	}
+
+	var retryAfter time.Duration
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+		retryAfter = time.Duration(s) * time.Second
+	}
+	return retryAfter, &WriteError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+}
+
+// retryable reports whether a failed request should be retried: network
+// errors and temporary server errors, but not cancellation.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var writeErr *WriteError
+	if errors.As(err, &writeErr) {
+		return writeErr.temporary()
+	}
+	return true
+}
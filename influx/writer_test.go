@@ -0,0 +1,251 @@
+package influx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer records the requests of a Writer and answers them with the
+// given status codes in turn, then with 204 No Content.
+type testServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   []string
+}
+
+func newTestServer(t *testing.T, statuses ...int) *testServer {
+	t.Helper()
+	s := &testServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, string(body))
+
+		status := http.StatusNoContent
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		if status != http.StatusNoContent {
+			http.Error(w, `{"code":"invalid","message":"test error"}`, status)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testServer) writer(t *testing.T, cfg Config) *Writer {
+	t.Helper()
+	cfg.URL = s.URL
+	cfg.Org = "my org"
+	cfg.Bucket = "ruuvi"
+	cfg.Token = "secret"
+	if cfg.RetryInterval == 0 {
+		cfg.RetryInterval = time.Millisecond
+	}
+	w, err := NewWriter(cfg)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	return w
+}
+
+func TestWriter_Batches(t *testing.T) {
+	s := newTestServer(t)
+	w := s.writer(t, Config{BatchSize: 2})
+	ctx := context.Background()
+
+	env := testEnvelope(t, "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F")
+	for range 3 {
+		if err := w.Add(ctx, env); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if len(s.requests) != 1 || strings.Count(s.bodies[0], "\n") != 2 {
+		t.Fatalf("expected one request with two lines, got %d requests: %q", len(s.requests), s.bodies)
+	}
+
+	if err := w.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if err := w.Flush(ctx); err != nil {
+		t.Fatalf("Flush() of empty buffer error = %v", err)
+	}
+	if len(s.requests) != 2 || strings.Count(s.bodies[1], "\n") != 1 {
+		t.Fatalf("expected a second request with one line, got %d requests: %q", len(s.requests), s.bodies)
+	}
+
+	req := s.requests[0]
+	if req.Method != http.MethodPost || req.URL.Path != "/api/v2/write" {
+		t.Errorf("request = %s %s", req.Method, req.URL.Path)
+	}
+	if q := req.URL.Query(); q.Get("org") != "my org" || q.Get("bucket") != "ruuvi" || q.Get("precision") != "ns" {
+		t.Errorf("query = %s", req.URL.RawQuery)
+	}
+	if got := req.Header.Get("Authorization"); got != "Token secret" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if !strings.HasPrefix(s.bodies[0], "ruuvi,format=5,mac=CB:B8:33:4C:88:4F temperature_c=24.3,") {
+		t.Errorf("body = %q", s.bodies[0])
+	}
+}
+
+func TestWriter_Retry(t *testing.T) {
+	s := newTestServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	w := s.writer(t, Config{})
+
+	if err := w.Write(context.Background(), []byte("ruuvi temperature_c=1\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if len(s.requests) != 3 || s.bodies[2] != "ruuvi temperature_c=1\n" {
+		t.Errorf("expected 3 attempts with the same body, got %q", s.bodies)
+	}
+}
+
+func TestWriter_RetriesExhausted(t *testing.T) {
+	s := newTestServer(t, 500, 500, 500)
+	w := s.writer(t, Config{MaxRetries: 2})
+
+	err := w.Write(context.Background(), []byte("ruuvi temperature_c=1\n"))
+	var writeErr *WriteError
+	if !errors.As(err, &writeErr) || writeErr.StatusCode != 500 || !strings.Contains(writeErr.Message, "test error") {
+		t.Fatalf("Write() error = %v, want *WriteError 500", err)
+	}
+	if len(s.requests) != 3 {
+		t.Errorf("expected 3 attempts, got %d", len(s.requests))
+	}
+}
+
+func TestWriter_NoRetry(t *testing.T) {
+	// Client errors are not retried, and negative MaxRetries disables retries
+	for _, tt := range []struct {
+		name       string
+		status     int
+		maxRetries int
+	}{
+		{"bad request", http.StatusBadRequest, 0},
+		{"retries disabled", http.StatusServiceUnavailable, -1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, tt.status)
+			w := s.writer(t, Config{MaxRetries: tt.maxRetries})
+
+			var writeErr *WriteError
+			if err := w.Write(context.Background(), []byte("bad\n")); !errors.As(err, &writeErr) || writeErr.StatusCode != tt.status {
+				t.Fatalf("Write() error = %v, want *WriteError %d", err, tt.status)
+			}
+			if len(s.requests) != 1 {
+				t.Errorf("expected 1 attempt, got %d", len(s.requests))
+			}
+		})
+	}
+}
+
+func TestWriter_FlushDropsFailedBatch(t *testing.T) {
+	s := newTestServer(t, http.StatusBadRequest)
+	w := s.writer(t, Config{})
+	ctx := context.Background()
+
+	if err := w.Add(ctx, testEnvelope(t, "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F")); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := w.Flush(ctx); err == nil || !strings.Contains(err.Error(), "1 lines dropped") {
+		t.Fatalf("Flush() error = %v, want dropped lines", err)
+	}
+	if err := w.Flush(ctx); err != nil || len(s.requests) != 1 {
+		t.Errorf("Flush() after failure = %v with %d requests, want nothing sent", err, len(s.requests))
+	}
+}
+
+func TestWriter_ContextCanceled(t *testing.T) {
+	s := newTestServer(t, 503, 503, 503)
+	w := s.writer(t, Config{RetryInterval: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := w.Write(ctx, []byte("ruuvi temperature_c=1\n"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Write() error = %v, want deadline exceeded", err)
+	}
+	if len(s.requests) != 1 {
+		t.Errorf("expected 1 attempt, got %d", len(s.requests))
+	}
+}
+
+func TestWriter_AddDuringFlush(t *testing.T) {
+	// Add keeps buffering while a batch is being sent
+	sending := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { close(sending) })
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	w, err := NewWriter(Config{URL: srv.URL, Org: "org", Bucket: "ruuvi"})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	ctx := context.Background()
+	env := testEnvelope(t, "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F")
+	if err := w.Add(ctx, env); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	flushed := make(chan error, 1)
+	go func() { flushed <- w.Flush(ctx) }()
+	<-sending
+
+	added := make(chan error, 1)
+	go func() { added <- w.Add(ctx, env) }()
+	select {
+	case err := <-added:
+		if err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Add() blocked while a batch was being sent")
+	}
+
+	w.mu.Lock()
+	lines := w.lines
+	w.mu.Unlock()
+	if lines != 1 {
+		t.Errorf("buffered lines = %d, want 1", lines)
+	}
+
+	release <- struct{}{}
+	if err := <-flushed; err != nil {
+		t.Errorf("Flush() error = %v", err)
+	}
+}
+
+func TestNewWriter_Invalid(t *testing.T) {
+	for _, cfg := range []Config{
+		{Org: "o", Bucket: "b"},
+		{URL: "http://localhost:8086", Bucket: "b"},
+		{URL: "localhost:8086", Org: "o", Bucket: "b"},
+	} {
+		if _, err := NewWriter(cfg); err == nil {
+			t.Errorf("NewWriter(%+v) = nil error, want error", cfg)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+// Package metrics exposes the latest readings of RuuviTags as Prometheus
+// metrics.
+//
+// A Collector keeps the latest reading of every tag, keyed by MAC address,
+// and renders them in the Prometheus text exposition format or in
+// OpenMetrics on scrape. Tags that have not been heard from within the stale
+// timeout drop out of the output. The Collector has no dependency on the
+// Prometheus client library; it implements http.Handler for the /metrics
+// endpoint itself.
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marcgeld/ruuvi/common"
+	"github.com/marcgeld/ruuvi/tag"
+)
+
+// Content types of the exposition formats.
+const (
+	ContentTypeText        = "text/plain; version=0.0.4; charset=utf-8"
+	ContentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// reading is the latest reading of a tag.
+type reading struct {
+	format     tag.DataFormat
+	values     tag.Quantities
+	sequence   *uint32
+	rssi       *int
+	receivedAt time.Time // ReceivedAt of the envelope, or seen if unknown
+	seen       time.Time // When the collector observed the reading
+}
+
+// Collector keeps the latest reading of every tag and counts decode errors,
+// see the package documentation. A Collector is safe for concurrent use.
+type Collector struct {
+	staleAfter time.Duration
+	now        func() time.Time
+
+	mu     sync.Mutex
+	tags   map[common.MACAddress]*reading
+	errors map[string]uint64
+}
+
+// NewCollector returns a Collector that drops tags not observed for
+// staleAfter. Tags are kept forever if staleAfter is 0.
+func NewCollector(staleAfter time.Duration) *Collector {
+	return &Collector{
+		staleAfter: staleAfter,
+		now:        time.Now,
+		tags:       make(map[common.MACAddress]*reading),
+		errors:     make(map[string]uint64),
+	}
+}
+
+// Observe records env as the latest reading of the tag returned by
+// tag.EnvelopeMAC. Returns false, and records nothing, if the envelope
+// carries no measurement or the MAC address of the tag is not known.
+func (c *Collector) Observe(env *tag.Envelope) bool {
+	mac, ok := tag.EnvelopeMAC(env)
+	if !ok || env.Data == nil {
+		return false
+	}
+
+	m := env.Data.Measurement()
+	if m == nil {
+		return false
+	}
+	r := &reading{
+		format:     env.Data.Format,
+		values:     m.Quantities(),
+		sequence:   m.MeasurementSequence,
+		rssi:       env.RSSI,
+		receivedAt: env.ReceivedAt,
+		seen:       c.now(),
+	}
+	if r.receivedAt.IsZero() {
+		r.receivedAt = r.seen
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tags[mac] = r
+	return true
+}
+
+// decodeErrorTypes maps the errors of package tag to the values of the
+// type label of ruuvi_decode_errors_total, in output order. Other errors,
+// such as malformed input, are counted as "other".
+var decodeErrorTypes = []struct {
+	err  error
+	name string
+}{
+	{tag.ErrEmpty, "empty"},
+	{tag.ErrUnknownFormat, "unknown_format"},
+	{tag.ErrInvalidLength, "invalid_length"},
+	{tag.ErrFormatMismatch, "format_mismatch"},
+	{tag.ErrKeyNotFound, "key_not_found"},
+	{tag.ErrCRCMismatch, "crc_mismatch"},
+	{tag.ErrMalformedAdvertisement, "malformed_advertisement"},
+	{tag.ErrNoRuuviData, "no_ruuvi_data"},
+	{nil, "other"},
+}
+
+// ObserveError counts an error returned while decoding a payload. Nil errors
+// are ignored.
+func (c *Collector) ObserveError(err error) {
+	if err == nil {
+		return
+	}
+
+	name := "other"
+	for _, t := range decodeErrorTypes {
+		if t.err != nil && errors.Is(err, t.err) {
+			name = t.name
+			break
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errors[name]++
+}
+
+// sample is one value of a metric, with labels beyond mac and format.
+type sample struct {
+	labels string // e.g. `axis="x"`, empty if none
+	value  float64
+}
+
+// gauge describes a per-tag gauge. samples returns nothing if the value is
+// not available in the reading.
+type gauge struct {
+	name, help string
+	samples    func(r *reading) []sample
+}
+
+// one returns a single sample of v.
+func one(v float64) []sample {
+	return []sample{{value: v}}
+}
+
+// gauges are the per-tag metrics, in output order. Values are in base units
+// as recommended for Prometheus.
+var gauges = []gauge{
+	{"ruuvi_temperature_celsius", "Temperature in degrees Celsius.", func(r *reading) []sample {
+		if t := r.values.Temperature; t != nil {
+			return one(t.Celsius)
+		}
+		return nil
+	}},
+	{"ruuvi_humidity_ratio", "Relative humidity, 0 to 1.", func(r *reading) []sample {
+		if h := r.values.Humidity; h != nil {
+			return one(h.Fraction())
+		}
+		return nil
+	}},
+	{"ruuvi_pressure_pascals", "Atmospheric pressure in pascals.", func(r *reading) []sample {
+		if p := r.values.Pressure; p != nil {
+			return one(float64(p.Pascals))
+		}
+		return nil
+	}},
+	{"ruuvi_acceleration_meters_per_second_squared", "Acceleration along each axis in m/s².", func(r *reading) []sample {
+		a := r.values.Acceleration
+		if a == nil {
+			return nil
+		}
+		x, y, z := a.MetersPerSecondSquared()
+		return []sample{{`axis="x"`, x}, {`axis="y"`, y}, {`axis="z"`, z}}
+	}},
+	{"ruuvi_battery_volts", "Battery voltage in volts.", func(r *reading) []sample {
+		if v := r.values.BatteryVoltage; v != nil {
+			return one(v.Volts())
+		}
+		return nil
+	}},
+	{"ruuvi_tx_power_dbm", "Transmit power in dBm.", func(r *reading) []sample {
+		if p := r.values.TxPower; p != nil {
+			return one(float64(p.DBm))
+		}
+		return nil
+	}},
+	{"ruuvi_movement_counter", "Movement counter of the tag, counts 0 to 254 and wraps around to 0.", func(r *reading) []sample {
+		if m := r.values.MovementCounter; m != nil {
+			return one(float64(m.Count))
+		}
+		return nil
+	}},
+	{"ruuvi_measurement_sequence", "Measurement sequence number of the latest reading.", func(r *reading) []sample {
+		if r.sequence != nil {
+			return one(float64(*r.sequence))
+		}
+		return nil
+	}},
+	{"ruuvi_rssi_dbm", "Received signal strength of the latest reading in dBm.", func(r *reading) []sample {
+		if r.rssi != nil {
+			return one(float64(*r.rssi))
+		}
+		return nil
+	}},
+	{"ruuvi_last_seen_timestamp_seconds", "Time the latest reading was received, in seconds since the Unix epoch.", func(r *reading) []sample {
+		return one(float64(r.receivedAt.UnixNano()) / 1e9)
+	}},
+}
+
+// WriteText writes the metrics in the Prometheus text exposition format,
+// version 0.0.4. Stale tags are dropped first.
+func (c *Collector) WriteText(w io.Writer) error {
+	return c.write(w, false)
+}
+
+// WriteOpenMetrics writes the metrics in the OpenMetrics text format.
+// Stale tags are dropped first.
+func (c *Collector) WriteOpenMetrics(w io.Writer) error {
+	return c.write(w, true)
+}
+
+// ServeHTTP serves the metrics, in OpenMetrics if the request accepts it and
+// in the Prometheus text format otherwise.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	openMetrics := strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+	if openMetrics {
+		w.Header().Set("Content-Type", ContentTypeOpenMetrics)
+	} else {
+		w.Header().Set("Content-Type", ContentTypeText)
+	}
+	_ = c.write(w, openMetrics)
+}
+
+func (c *Collector) write(w io.Writer, openMetrics bool) error {
+	c.mu.Lock()
+	c.dropStale()
+	macs := make([]common.MACAddress, 0, len(c.tags))
+	readings := make(map[common.MACAddress]reading, len(c.tags))
+	for mac, r := range c.tags {
+		macs = append(macs, mac)
+		readings[mac] = *r
+	}
+	errorCounts := make(map[string]uint64, len(c.errors))
+	for name, n := range c.errors {
+		errorCounts[name] = n
+	}
+	c.mu.Unlock()
+
+	slices.SortFunc(macs, func(a, b common.MACAddress) int { return strings.Compare(a.String(), b.String()) })
+
+	bw := bufio.NewWriter(w)
+	for _, g := range gauges {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+		for _, mac := range macs {
+			r := readings[mac]
+			for _, s := range g.samples(&r) {
+				labels := fmt.Sprintf(`mac="%s",format="%s"`, mac, r.format)
+				if s.labels != "" {
+					labels += "," + s.labels
+				}
+				fmt.Fprintf(bw, "%s{%s} %s\n", g.name, labels, formatValue(s.value))
+			}
+		}
+	}
+
+	// OpenMetrics names the counter family without the _total suffix
+	const counter = "ruuvi_decode_errors"
+	family := counter + "_total"
+	if openMetrics {
+		family = counter
+	}
+	fmt.Fprintf(bw, "# HELP %s Payloads that failed to decode, by error type.\n# TYPE %s counter\n", family, family)
+	for _, t := range decodeErrorTypes {
+		fmt.Fprintf(bw, "%s_total{type=\"%s\"} %d\n", counter, t.name, errorCounts[t.name])
+	}
+
+	if openMetrics {
+		bw.WriteString("# EOF\n")
+	}
+	return bw.Flush()
+}
+
+// dropStale removes the tags not observed within the stale timeout. The
+// caller holds c.mu.
+func (c *Collector) dropStale() {
+	if c.staleAfter <= 0 {
+		return
+	}
+	cutoff := c.now().Add(-c.staleAfter)
+	for mac, r := range c.tags {
+		if r.seen.Before(cutoff) {
+			delete(c.tags, mac)
+		}
+	}
+}
+
+// formatValue formats a sample value in the shortest form that parses back
+// to the same float64.
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,181 @@
+package metrics
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcgeld/ruuvi/common"
+	"github.com/marcgeld/ruuvi/tag"
+)
+
+const format5Payload = "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F"
+
+func testEnvelope(t *testing.T, payload string) *tag.Envelope {
+	t.Helper()
+	data, err := hex.DecodeString(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := tag.NewEnvelope(data)
+	if err != nil {
+		t.Fatalf("NewEnvelope() error = %v", err)
+	}
+	return env
+}
+
+func writeText(t *testing.T, c *Collector) string {
+	t.Helper()
+	var b strings.Builder
+	if err := c.WriteText(&b); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	return b.String()
+}
+
+func TestCollector_WriteText(t *testing.T) {
+	c := NewCollector(0)
+
+	env := testEnvelope(t, format5Payload)
+	env.ReceivedAt = time.Unix(1735787045, 500_000_000)
+	rssi := -67
+	env.RSSI = &rssi
+	if !c.Observe(env) {
+		t.Fatal("Observe() = false, want true")
+	}
+
+	got := writeText(t, c)
+	labels := `{mac="CB:B8:33:4C:88:4F",format="5"`
+	for _, want := range []string{
+		"# HELP ruuvi_temperature_celsius Temperature in degrees Celsius.\n# TYPE ruuvi_temperature_celsius gauge\n",
+		"ruuvi_temperature_celsius" + labels + "} 24.3\n",
+		"ruuvi_humidity_ratio" + labels + "} 0.5349\n",
+		"ruuvi_pressure_pascals" + labels + "} 100044\n",
+		"ruuvi_acceleration_meters_per_second_squared" + labels + `,axis="z"} 10.1596894` + "\n",
+		"ruuvi_battery_volts" + labels + "} 2.977\n",
+		"ruuvi_tx_power_dbm" + labels + "} 4\n",
+		"ruuvi_movement_counter" + labels + "} 66\n",
+		"ruuvi_measurement_sequence" + labels + "} 205\n",
+		"ruuvi_rssi_dbm" + labels + "} -67\n",
+		"ruuvi_last_seen_timestamp_seconds" + labels + "} 1.7357870455e+09\n",
+		"# TYPE ruuvi_decode_errors_total counter\n",
+		`ruuvi_decode_errors_total{type="unknown_format"} 0` + "\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "# EOF") {
+		t.Error("text format must not end with # EOF")
+	}
+}
+
+func TestCollector_LatestReadingPerTag(t *testing.T) {
+	c := NewCollector(0)
+
+	// Format 6 carries no full MAC address: it needs the Bluetooth address
+	f6 := testEnvelope(t, "06170C5668C79E007000C90501D9FFCD004C884F")
+	if c.Observe(f6) {
+		t.Error("Observe() without MAC address = true, want false")
+	}
+	mac := common.MACAddress{0xCB, 0xB8, 0x33, 0x4C, 0x88, 0x50}
+	f6.Address = &mac
+	if !c.Observe(f6) {
+		t.Error("Observe() with Bluetooth address = false, want true")
+	}
+
+	c.Observe(testEnvelope(t, format5Payload))
+	temp := 21.5
+	c.Observe(&tag.Envelope{Data: &tag.DecodedData{Format: tag.Format5, Format5: &tag.Format5Data{
+		Temperature: &temp,
+		MACAddress:  &common.MACAddress{0xCB, 0xB8, 0x33, 0x4C, 0x88, 0x4F},
+	}}})
+
+	got := writeText(t, c)
+	if !strings.Contains(got, `ruuvi_temperature_celsius{mac="CB:B8:33:4C:88:4F",format="5"} 21.5`+"\n") ||
+		!strings.Contains(got, `ruuvi_temperature_celsius{mac="CB:B8:33:4C:88:50",format="6"} 29.5`+"\n") {
+		t.Errorf("expected latest temperature of both tags, got:\n%s", got)
+	}
+	// Values missing from the latest reading are not exported
+	if strings.Contains(got, `ruuvi_pressure_pascals{mac="CB:B8:33:4C:88:4F"`) {
+		t.Errorf("expected no pressure for the latest Format 5 reading, got:\n%s", got)
+	}
+	// Tags are sorted by MAC address
+	if strings.Index(got, `mac="CB:B8:33:4C:88:4F"`) > strings.Index(got, `mac="CB:B8:33:4C:88:50"`) {
+		t.Errorf("expected tags in MAC order, got:\n%s", got)
+	}
+}
+
+func TestCollector_Stale(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := NewCollector(5 * time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Observe(testEnvelope(t, format5Payload))
+
+	now = now.Add(5 * time.Minute)
+	if got := writeText(t, c); !strings.Contains(got, "ruuvi_temperature_celsius{") {
+		t.Errorf("expected tag before timeout, got:\n%s", got)
+	}
+
+	now = now.Add(time.Second)
+	if got := writeText(t, c); strings.Contains(got, "ruuvi_temperature_celsius{") {
+		t.Errorf("expected stale tag to drop out, got:\n%s", got)
+	}
+}
+
+func TestCollector_ObserveError(t *testing.T) {
+	c := NewCollector(0)
+
+	for _, payload := range []string{"", "42", "0512"} {
+		data, _ := hex.DecodeString(payload)
+		_, err := tag.NewEnvelope(data)
+		c.ObserveError(err)
+	}
+	c.ObserveError(fmt.Errorf("line 3: %w", &tag.UnknownFormatError{Format: 0x43}))
+	c.ObserveError(errors.New("invalid hex"))
+	c.ObserveError(nil)
+
+	got := writeText(t, c)
+	for _, want := range []string{
+		`ruuvi_decode_errors_total{type="empty"} 1`,
+		`ruuvi_decode_errors_total{type="unknown_format"} 2`,
+		`ruuvi_decode_errors_total{type="invalid_length"} 1`,
+		`ruuvi_decode_errors_total{type="crc_mismatch"} 0`,
+		`ruuvi_decode_errors_total{type="other"} 1`,
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("expected output to contain %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestCollector_ServeHTTP(t *testing.T) {
+	c := NewCollector(0)
+	c.Observe(testEnvelope(t, format5Payload))
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != ContentTypeText {
+		t.Errorf("Content-Type = %q, want %q", ct, ContentTypeText)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5")
+	rec = httptest.NewRecorder()
+	c.ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if ct := rec.Header().Get("Content-Type"); ct != ContentTypeOpenMetrics {
+		t.Errorf("Content-Type = %q, want %q", ct, ContentTypeOpenMetrics)
+	}
+	if !strings.HasSuffix(body, "# EOF\n") || !strings.Contains(body, "# TYPE ruuvi_decode_errors counter\n") ||
+		!strings.Contains(body, `ruuvi_decode_errors_total{type="empty"} 0`) {
+		t.Errorf("unexpected OpenMetrics output:\n%s", body)
+	}
+}
@@ -0,0 +1,227 @@
+// Package motion derives orientation and motion information from RuuviTag
+// acceleration readings.
+//
+// Magnitude and Tilt work on a single X/Y/Z reading in G. A Detector follows
+// the readings of one tag and emits events for orientation changes (for
+// example a door opening or closing), free fall, impacts and movement counted
+// by the accelerometer.
+package motion
+
+import (
+	"math"
+
+	"github.com/marcgeld/ruuvi/stream"
+	"github.com/marcgeld/ruuvi/tag"
+)
+
+// Default Detector thresholds, used for zero Config fields.
+const (
+	DefaultOrientationThreshold = 30.0 // degrees
+	DefaultStationaryTolerance  = 0.15 // G
+	DefaultFreeFallThreshold    = 0.3  // G
+	DefaultImpactThreshold      = 2.0  // G
+)
+
+// Sample is a single acceleration reading of a tag.
+type Sample struct {
+	X, Y, Z         float64        // Acceleration in G
+	MovementCounter *uint8         // Movement counter, nil if the format has none
+	Sequence        *uint32        // Measurement sequence number, nil if the format has none
+	Format          tag.DataFormat // Data format, for telling sequence wraparounds from restarts
+}
+
+// SampleFromMeasurement returns the acceleration reading of a measurement.
+// Returns false if any acceleration axis is unavailable.
+func SampleFromMeasurement(m *tag.Measurement) (Sample, bool) {
+	if m == nil || m.AccelerationX == nil || m.AccelerationY == nil || m.AccelerationZ == nil {
+		return Sample{}, false
+	}
+
+	return Sample{
+		X:               *m.AccelerationX,
+		Y:               *m.AccelerationY,
+		Z:               *m.AccelerationZ,
+		MovementCounter: m.MovementCounter,
+		Sequence:        m.MeasurementSequence,
+		Format:          m.Format,
+	}, true
+}
+
+// Magnitude returns the total acceleration in G. A tag at rest measures about 1 G.
+func Magnitude(x, y, z float64) float64 {
+	return math.Sqrt(x*x + y*y + z*z)
+}
+
+// Tilt returns the pitch and roll angles in degrees of a tag at rest, derived
+// from the direction of gravity. Pitch is the rotation around the Y axis
+// (-90 to 90), roll the rotation around the X axis (-180 to 180). A tag lying
+// flat with Z pointing up has zero pitch and roll.
+func Tilt(x, y, z float64) (pitch, roll float64) {
+	pitch = math.Atan2(-x, math.Sqrt(y*y+z*z)) * 180 / math.Pi
+	roll = math.Atan2(y, z) * 180 / math.Pi
+	return pitch, roll
+}
+
+// Angle returns the angle in degrees between two acceleration readings.
+// For tags at rest this is how far the tag was turned between them.
+// Returns NaN if either reading is zero.
+func Angle(a, b Sample) float64 {
+	dot := a.X*b.X + a.Y*b.Y + a.Z*b.Z
+	cos := dot / (Magnitude(a.X, a.Y, a.Z) * Magnitude(b.X, b.Y, b.Z))
+	return math.Acos(math.Max(-1, math.Min(1, cos))) * 180 / math.Pi
+}
+
+// EventType identifies the kind of a motion event.
+type EventType int
+
+const (
+	// EventOrientationChange is emitted when a tag at rest has turned by more
+	// than the orientation threshold since the last stable orientation.
+	EventOrientationChange EventType = iota + 1
+
+	// EventFreeFall is emitted when the total acceleration drops below the
+	// free fall threshold.
+	EventFreeFall
+
+	// EventImpact is emitted when the total acceleration exceeds the impact threshold.
+	EventImpact
+
+	// EventMovement is emitted when the movement counter advanced without
+	// any other event being visible in the reading. The accelerometer counts
+	// movement between two broadcasts, from short taps to slow movement that
+	// never exceeds the other thresholds.
+	EventMovement
+)
+
+// String returns the name of the event type.
+func (t EventType) String() string {
+	switch t {
+	case EventOrientationChange:
+		return "orientation_change"
+	case EventFreeFall:
+		return "free_fall"
+	case EventImpact:
+		return "impact"
+	case EventMovement:
+		return "movement"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a motion event detected in a reading.
+type Event struct {
+	Type      EventType
+	Magnitude float64 // Total acceleration of the reading in G
+	Pitch     float64 // Pitch of the reading in degrees
+	Roll      float64 // Roll of the reading in degrees
+	Angle     float64 // Rotation since the previous stable orientation in degrees, for EventOrientationChange
+	Movements int     // Movement counter increase, for EventMovement
+}
+
+// Config holds the Detector thresholds. Zero fields use the defaults.
+type Config struct {
+	OrientationThreshold float64 // Minimum rotation in degrees for an orientation change
+	StationaryTolerance  float64 // Maximum deviation from 1 G in G for a reading to count as at rest
+	FreeFallThreshold    float64 // Total acceleration in G below which a free fall is reported
+	ImpactThreshold      float64 // Total acceleration in G above which an impact is reported
+}
+
+// Detector detects motion events in successive readings of a single tag.
+// It is not safe for concurrent use; use one Detector per tag.
+type Detector struct {
+	cfg Config
+
+	reference    *Sample // Last stable orientation
+	lastCounter  *uint8
+	lastSequence *uint32
+}
+
+// NewDetector returns a Detector with the given thresholds.
+func NewDetector(cfg Config) *Detector {
+	if cfg.OrientationThreshold == 0 {
+		cfg.OrientationThreshold = DefaultOrientationThreshold
+	}
+	if cfg.StationaryTolerance == 0 {
+		cfg.StationaryTolerance = DefaultStationaryTolerance
+	}
+	if cfg.FreeFallThreshold == 0 {
+		cfg.FreeFallThreshold = DefaultFreeFallThreshold
+	}
+	if cfg.ImpactThreshold == 0 {
+		cfg.ImpactThreshold = DefaultImpactThreshold
+	}
+	return &Detector{cfg: cfg}
+}
+
+// Update processes the next reading of the tag and returns the events it
+// triggers, if any. The first reading establishes the reference orientation
+// and movement counter, so it cannot trigger orientation changes or movement;
+// free fall and impacts only depend on the reading itself and are reported
+// for any reading.
+//
+// A movement counter that went down has wrapped around, unless the
+// measurement sequence shows a restart of the tag (see
+// stream.ClassifySequence): the counter then holds the movements since the
+// restart. Late repeats of earlier measurements are ignored for movement.
+func (d *Detector) Update(s Sample) []Event {
+	magnitude := Magnitude(s.X, s.Y, s.Z)
+	pitch, roll := Tilt(s.X, s.Y, s.Z)
+	event := func(t EventType) Event {
+		return Event{Type: t, Magnitude: magnitude, Pitch: pitch, Roll: roll}
+	}
+
+	var events []Event
+
+	switch {
+	case magnitude < d.cfg.FreeFallThreshold:
+		events = append(events, event(EventFreeFall))
+	case magnitude > d.cfg.ImpactThreshold:
+		events = append(events, event(EventImpact))
+	}
+
+	// Orientation is only meaningful while gravity dominates the reading
+	if math.Abs(magnitude-1) <= d.cfg.StationaryTolerance {
+		switch {
+		case d.reference == nil:
+			d.reference = &s
+		default:
+			if angle := Angle(*d.reference, s); angle >= d.cfg.OrientationThreshold {
+				e := event(EventOrientationChange)
+				e.Angle = angle
+				events = append(events, e)
+				d.reference = &s
+			}
+		}
+	}
+
+	status := stream.SequenceUntracked
+	if s.Sequence != nil && d.lastSequence != nil {
+		status = stream.ClassifySequence(s.Format, *d.lastSequence, *s.Sequence)
+	}
+	if status == stream.SequenceDuplicate {
+		return events
+	}
+
+	if s.MovementCounter != nil {
+		if d.lastCounter != nil {
+			moved := (int(*s.MovementCounter) - int(*d.lastCounter) + tag.MovementCounterModulus) % tag.MovementCounterModulus
+			if status == stream.SequenceReboot {
+				moved = int(*s.MovementCounter)
+			}
+			if moved > 0 && len(events) == 0 {
+				e := event(EventMovement)
+				e.Movements = moved
+				events = append(events, e)
+			}
+		}
+		counter := *s.MovementCounter
+		d.lastCounter = &counter
+	}
+	if s.Sequence != nil {
+		seq := *s.Sequence
+		d.lastSequence = &seq
+	}
+
+	return events
+}
@@ -0,0 +1,226 @@
+package motion
+
+import (
+	"math"
+	"testing"
+
+	"github.com/marcgeld/ruuvi/tag"
+)
+
+func almostEqual(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func counter(v uint8) *uint8 { return &v }
+
+func sequence(v uint32) *uint32 { return &v }
+
+func TestMagnitude(t *testing.T) {
+	if got := Magnitude(0.004, -0.004, 1.036); !almostEqual(got, 1.03602, 0.00001) {
+		t.Errorf("Magnitude() = %v, want 1.03602", got)
+	}
+	if got := Magnitude(3, 4, 12); got != 13 {
+		t.Errorf("Magnitude(3, 4, 12) = %v, want 13", got)
+	}
+}
+
+func TestTilt(t *testing.T) {
+	s2 := math.Sqrt2 / 2
+
+	tests := []struct {
+		name    string
+		x, y, z float64
+		pitch   float64
+		roll    float64
+	}{
+		{name: "flat", x: 0, y: 0, z: 1, pitch: 0, roll: 0},
+		{name: "upside down", x: 0, y: 0, z: -1, pitch: 0, roll: 180},
+		{name: "on edge", x: 0, y: 1, z: 0, pitch: 0, roll: 90},
+		{name: "nose down", x: 1, y: 0, z: 0, pitch: -90, roll: 0},
+		{name: "tilted 45", x: 0, y: s2, z: s2, pitch: 0, roll: 45},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pitch, roll := Tilt(tt.x, tt.y, tt.z)
+			if !almostEqual(pitch, tt.pitch, 1e-9) || !almostEqual(roll, tt.roll, 1e-9) {
+				t.Errorf("Tilt() = (%v, %v), want (%v, %v)", pitch, roll, tt.pitch, tt.roll)
+			}
+		})
+	}
+}
+
+func TestAngle(t *testing.T) {
+	if got := Angle(Sample{Z: 1}, Sample{Y: 1}); !almostEqual(got, 90, 1e-9) {
+		t.Errorf("Angle() = %v, want 90", got)
+	}
+	if got := Angle(Sample{Z: 1}, Sample{Z: 1.02}); !almostEqual(got, 0, 1e-6) {
+		t.Errorf("Angle() = %v, want 0", got)
+	}
+	if got := Angle(Sample{}, Sample{Z: 1}); !math.IsNaN(got) {
+		t.Errorf("Angle() with zero reading = %v, want NaN", got)
+	}
+}
+
+func TestSampleFromMeasurement(t *testing.T) {
+	decoded, err := tag.ParseManufacturerData([]byte{
+		0x05, 0x12, 0xFC, 0x53, 0x94, 0xC3, 0x7C, 0x00, 0x04, 0xFF, 0xFC, 0x04, 0x0C,
+		0xAC, 0x36, 0x42, 0x00, 0xCD, 0xCB, 0xB8, 0x33, 0x4C, 0x88, 0x4F,
+	})
+	if err != nil {
+		t.Fatalf("ParseManufacturerData failed: %v", err)
+	}
+
+	s, ok := SampleFromMeasurement(decoded)
+	if !ok {
+		t.Fatal("SampleFromMeasurement() = false, want true")
+	}
+	if s.X != 0.004 || s.Y != -0.004 || s.Z != 1.036 || s.MovementCounter == nil || *s.MovementCounter != 66 ||
+		s.Sequence == nil || *s.Sequence != 205 {
+		t.Errorf("SampleFromMeasurement() = %+v", s)
+	}
+
+	if _, ok := SampleFromMeasurement(&tag.Measurement{}); ok {
+		t.Error("SampleFromMeasurement() without acceleration = true, want false")
+	}
+	if _, ok := SampleFromMeasurement(nil); ok {
+		t.Error("SampleFromMeasurement(nil) = true, want false")
+	}
+}
+
+func TestDetector(t *testing.T) {
+	d := NewDetector(Config{})
+
+	steps := []struct {
+		name   string
+		sample Sample
+		want   []EventType
+	}{
+		{name: "closed door at rest", sample: Sample{Z: 1, MovementCounter: counter(10)}},
+		{name: "small wobble", sample: Sample{X: 0.1, Z: 0.99, MovementCounter: counter(10)}},
+		{name: "door opened", sample: Sample{Y: 1, MovementCounter: counter(11)}, want: []EventType{EventOrientationChange}},
+		{name: "still open", sample: Sample{Y: 1.01, MovementCounter: counter(11)}},
+		{name: "movement", sample: Sample{Y: 1, MovementCounter: counter(13)}, want: []EventType{EventMovement}},
+		{name: "dropped", sample: Sample{X: 0.05, Y: 0.1, MovementCounter: counter(14)}, want: []EventType{EventFreeFall}},
+		{name: "hit the floor", sample: Sample{Z: 3.5, MovementCounter: counter(15)}, want: []EventType{EventImpact}},
+		{name: "lying flat", sample: Sample{Z: 1, MovementCounter: counter(15)}, want: []EventType{EventOrientationChange}},
+		{name: "counter wraparound", sample: Sample{Z: 1, MovementCounter: counter(0)}, want: []EventType{EventMovement}},
+		{name: "no counter", sample: Sample{Z: 1}},
+	}
+
+	for _, step := range steps {
+		events := d.Update(step.sample)
+		if len(events) != len(step.want) {
+			t.Fatalf("%s: got %d events %+v, want %v", step.name, len(events), events, step.want)
+		}
+		for i, e := range events {
+			if e.Type != step.want[i] {
+				t.Errorf("%s: event %d = %s, want %s", step.name, i, e.Type, step.want[i])
+			}
+		}
+	}
+}
+
+func TestDetector_EventDetails(t *testing.T) {
+	d := NewDetector(Config{OrientationThreshold: 10})
+	d.Update(Sample{Z: 1, MovementCounter: counter(250)})
+
+	events := d.Update(Sample{Y: math.Sin(15 * math.Pi / 180), Z: math.Cos(15 * math.Pi / 180)})
+	if len(events) != 1 || events[0].Type != EventOrientationChange {
+		t.Fatalf("events = %+v, want one orientation change", events)
+	}
+	if !almostEqual(events[0].Angle, 15, 1e-9) || !almostEqual(events[0].Roll, 15, 1e-9) {
+		t.Errorf("event = %+v, want angle and roll 15", events[0])
+	}
+
+	d.Update(Sample{Y: math.Sin(15 * math.Pi / 180), Z: math.Cos(15 * math.Pi / 180), MovementCounter: counter(252)})
+	events = d.Update(Sample{Y: math.Sin(15 * math.Pi / 180), Z: math.Cos(15 * math.Pi / 180), MovementCounter: counter(1)})
+	if len(events) != 1 || events[0].Type != EventMovement || events[0].Movements != 4 {
+		t.Errorf("events = %+v, want one movement event with 4 movements", events)
+	}
+}
+
+func TestDetector_FirstReading(t *testing.T) {
+	d := NewDetector(Config{})
+	events := d.Update(Sample{Z: 3.5, MovementCounter: counter(40)})
+	if len(events) != 1 || events[0].Type != EventImpact {
+		t.Errorf("events = %+v, want one impact", events)
+	}
+}
+
+func TestDetector_Reboot(t *testing.T) {
+	d := NewDetector(Config{})
+	d.Update(Sample{Z: 1, MovementCounter: counter(200), Sequence: sequence(40000), Format: tag.Format5})
+
+	events := d.Update(Sample{Z: 1, MovementCounter: counter(0), Sequence: sequence(0), Format: tag.Format5})
+	if len(events) != 0 {
+		t.Errorf("events after reboot = %+v, want none", events)
+	}
+
+	events = d.Update(Sample{Z: 1, MovementCounter: counter(2), Sequence: sequence(1), Format: tag.Format5})
+	if len(events) != 1 || events[0].Type != EventMovement || events[0].Movements != 2 {
+		t.Errorf("events = %+v, want one movement event with 2 movements", events)
+	}
+
+	d.Update(Sample{Z: 1, MovementCounter: counter(9), Sequence: sequence(5000), Format: tag.Format5})
+	events = d.Update(Sample{Z: 1, MovementCounter: counter(3), Sequence: sequence(2), Format: tag.Format5})
+	if len(events) != 1 || events[0].Type != EventMovement || events[0].Movements != 3 {
+		t.Errorf("events = %+v, want one movement event with the 3 movements since the reboot", events)
+	}
+}
+
+func TestDetector_SequenceWraparound(t *testing.T) {
+	tests := []struct {
+		name          string
+		format        tag.DataFormat
+		last, next    uint32
+		wantMovements int
+	}{
+		{name: "format 5 wraparound", format: tag.Format5, last: 65534, next: 0, wantMovements: 7},
+		{name: "format 5 wraparound with lost measurements", format: tag.Format5, last: 65530, next: 12, wantMovements: 7},
+		{name: "format 6 wraparound", format: tag.Format6, last: 255, next: 0, wantMovements: 7},
+		{name: "format 6 wraparound with lost measurements", format: tag.Format6, last: 240, next: 5, wantMovements: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDetector(Config{})
+			d.Update(Sample{Z: 1, MovementCounter: counter(250), Sequence: sequence(tt.last), Format: tt.format})
+
+			events := d.Update(Sample{Z: 1, MovementCounter: counter(2), Sequence: sequence(tt.next), Format: tt.format})
+			if len(events) != 1 || events[0].Type != EventMovement || events[0].Movements != tt.wantMovements {
+				t.Errorf("events = %+v, want one movement event with %d movements", events, tt.wantMovements)
+			}
+		})
+	}
+}
+
+func TestDetector_OutOfOrder(t *testing.T) {
+	d := NewDetector(Config{})
+	d.Update(Sample{Z: 1, MovementCounter: counter(10), Sequence: sequence(100), Format: tag.Format5})
+	d.Update(Sample{Z: 1, MovementCounter: counter(12), Sequence: sequence(102), Format: tag.Format5})
+
+	events := d.Update(Sample{Z: 1, MovementCounter: counter(11), Sequence: sequence(101), Format: tag.Format5})
+	if len(events) != 0 {
+		t.Errorf("events for late measurement = %+v, want none", events)
+	}
+
+	events = d.Update(Sample{Z: 1, MovementCounter: counter(13), Sequence: sequence(103), Format: tag.Format5})
+	if len(events) != 1 || events[0].Type != EventMovement || events[0].Movements != 1 {
+		t.Errorf("events = %+v, want one movement event with 1 movement", events)
+	}
+}
+
+func TestEventType_String(t *testing.T) {
+	for typ, want := range map[EventType]string{
+		EventOrientationChange: "orientation_change",
+		EventFreeFall:          "free_fall",
+		EventImpact:            "impact",
+		EventMovement:          "movement",
+		EventType(0):           "unknown",
+	} {
+		if got := typ.String(); got != want {
+			t.Errorf("EventType(%d).String() = %q, want %q", typ, got, want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package stream
+
+import (
+	"sync"
+
+	"github.com/marcgeld/ruuvi/common"
+	"github.com/marcgeld/ruuvi/tag"
+)
+
+// MovementEvent reports the movements of a tag between two readings.
+type MovementEvent struct {
+	MAC       common.MACAddress
+	Movements uint32 // Movements since the previous reading
+	Total     uint64 // Cumulative movements since the tag was first seen
+	Reboot    bool   // The tag restarted since the previous reading
+}
+
+// movementState is the per-tag state of a MovementTracker.
+type movementState struct {
+	counter   uint8
+	sequence  uint32
+	sequenced bool // sequence holds the sequence number of the last reading
+	total     uint64
+}
+
+// MovementTracker turns the movement counters of successive readings into a
+// monotonic cumulative movement count per tag.
+//
+// The movement counter wraps from 254 to 0 and restarts at 0 when the tag
+// reboots. The measurement sequence number tells the two apart: a counter
+// that went down while the sequence advanced has wrapped around, while a
+// sequence that jumped back marks a reboot, after which the counter value is
+// the number of movements since the restart. A counter that went down without
+// the sequence advancing by a plausible step since the previous reading is a
+// reboot as well. Readings without a sequence number are assumed to wrap
+// around.
+type MovementTracker struct {
+	sequences *SequenceTracker
+
+	mu   sync.Mutex
+	tags map[common.MACAddress]*movementState
+}
+
+// NewMovementTracker returns an empty MovementTracker.
+func NewMovementTracker() *MovementTracker {
+	return &MovementTracker{
+		sequences: NewSequenceTracker(),
+		tags:      make(map[common.MACAddress]*movementState),
+	}
+}
+
+// Observe processes the next reading of a tag. It returns the movements
+// since the previous reading and true if the tag moved. Readings without a
+// MAC address or movement counter, and repeats of the previous measurement,
+// return false and leave the state unchanged.
+func (t *MovementTracker) Observe(m *tag.Measurement) (MovementEvent, bool) {
+	if m == nil || m.MACAddress == nil {
+		return MovementEvent{}, false
+	}
+	return t.observe(*m.MACAddress, m)
+}
+
+// ObserveEnvelope processes the next reading of a tag like Observe, keyed by
+// the MAC address returned by tag.EnvelopeMAC.
+func (t *MovementTracker) ObserveEnvelope(e *tag.Envelope) (MovementEvent, bool) {
+	mac, ok := tag.EnvelopeMAC(e)
+	if !ok || e.Data == nil {
+		return MovementEvent{}, false
+	}
+	return t.observe(mac, e.Data.Measurement())
+}
+
+func (t *MovementTracker) observe(mac common.MACAddress, m *tag.Measurement) (MovementEvent, bool) {
+	if m == nil || m.MovementCounter == nil {
+		return MovementEvent{}, false
+	}
+	counter := *m.MovementCounter
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var seq uint32
+	status := SequenceUntracked
+	modulus := SequenceModulus(m.Format)
+	sequenced := m.MeasurementSequence != nil && *m.MeasurementSequence < modulus
+	if sequenced {
+		seq = *m.MeasurementSequence
+		status = t.sequences.Observe(mac, m.Format, seq).Status
+	}
+	if status == SequenceDuplicate {
+		return MovementEvent{}, false
+	}
+
+	state, ok := t.tags[mac]
+	if !ok {
+		t.tags[mac] = &movementState{counter: counter, sequence: seq, sequenced: sequenced}
+		return MovementEvent{MAC: mac}, false
+	}
+
+	reboot := status == SequenceReboot
+	if !reboot && counter < state.counter && sequenced && state.sequenced {
+		// A wraparound of the counter takes the sequence forward as well.
+		step, _ := classifySequence(modulus, state.sequence, seq)
+		reboot = step != SequenceNew && step != SequenceGap
+	}
+
+	event := MovementEvent{MAC: mac, Reboot: reboot}
+	if event.Reboot {
+		event.Movements = uint32(counter)
+	} else {
+		event.Movements = (uint32(counter) + tag.MovementCounterModulus - uint32(state.counter)) % tag.MovementCounterModulus
+	}
+
+	state.counter = counter
+	state.sequence, state.sequenced = seq, sequenced
+	state.total += uint64(event.Movements)
+	event.Total = state.total
+
+	return event, event.Movements > 0
+}
+
+// Total returns the cumulative movements of the tag with the given MAC address.
+// Returns false if the tag has not been seen.
+func (t *MovementTracker) Total(mac common.MACAddress) (uint64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, ok := t.tags[mac]
+	if !ok {
+		return 0, false
+	}
+	return state.total, true
+}
+
+// Forget drops the state of the tag with the given MAC address.
+func (t *MovementTracker) Forget(mac common.MACAddress) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.tags, mac)
+	t.sequences.Forget(mac)
+}
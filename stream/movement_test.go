@@ -0,0 +1,168 @@
+package stream
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/marcgeld/ruuvi/common"
+	"github.com/marcgeld/ruuvi/tag"
+)
+
+func movementReading(mac common.MACAddress, counter uint8, seq *uint32) *tag.Measurement {
+	return &tag.Measurement{Format: tag.Format5, MACAddress: &mac, MovementCounter: &counter, MeasurementSequence: seq}
+}
+
+func seqPtr(v uint32) *uint32 { return &v }
+
+func TestMovementTracker_Observe(t *testing.T) {
+	tr := NewMovementTracker()
+
+	steps := []struct {
+		name      string
+		counter   uint8
+		seq       uint32
+		moved     bool
+		movements uint32
+		total     uint64
+		reboot    bool
+	}{
+		{name: "first reading", counter: 250, seq: 1000},
+		{name: "repeat", counter: 250, seq: 1000},
+		{name: "no movement", counter: 250, seq: 1001},
+		{name: "moved", counter: 252, seq: 1002, moved: true, movements: 2, total: 2},
+		{name: "wraparound", counter: 3, seq: 1003, moved: true, movements: 6, total: 8},
+		{name: "wraparound across gap", counter: 1, seq: 1010, moved: true, movements: 253, total: 261},
+		{name: "reboot", counter: 4, seq: 2, moved: true, movements: 4, total: 265, reboot: true},
+		{name: "after reboot", counter: 5, seq: 3, moved: true, movements: 1, total: 266},
+	}
+
+	for _, step := range steps {
+		event, moved := tr.Observe(movementReading(testMAC, step.counter, seqPtr(step.seq)))
+		if moved != step.moved || event.Movements != step.movements || event.Reboot != step.reboot {
+			t.Errorf("%s: Observe() = %+v, %v; want movements %d, reboot %v, moved %v",
+				step.name, event, moved, step.movements, step.reboot, step.moved)
+		}
+		if step.moved && event.Total != step.total {
+			t.Errorf("%s: Total = %d, want %d", step.name, event.Total, step.total)
+		}
+	}
+
+	if total, ok := tr.Total(testMAC); !ok || total != 266 {
+		t.Errorf("Total() = %d, %v; want 266, true", total, ok)
+	}
+}
+
+func TestMovementTracker_RebootFromHighSequence(t *testing.T) {
+	tr := NewMovementTracker()
+	tr.Observe(movementReading(testMAC, 200, seqPtr(40000)))
+
+	event, moved := tr.Observe(movementReading(testMAC, 3, seqPtr(0)))
+	if !moved || event.Movements != 3 || !event.Reboot || event.Total != 3 {
+		t.Errorf("Observe() = %+v, %v; want 3 movements after reboot", event, moved)
+	}
+}
+
+func TestMovementTracker_WithoutSequence(t *testing.T) {
+	tr := NewMovementTracker()
+	tr.Observe(movementReading(testMAC, 254, nil))
+
+	event, moved := tr.Observe(movementReading(testMAC, 1, nil))
+	if !moved || event.Movements != 2 || event.Reboot {
+		t.Errorf("Observe() = %+v, %v; want 2 movements from wraparound", event, moved)
+	}
+}
+
+func TestMovementTracker_Untracked(t *testing.T) {
+	tr := NewMovementTracker()
+
+	for _, m := range []*tag.Measurement{
+		nil,
+		{Format: tag.Format5, MACAddress: &testMAC},
+		{Format: tag.Format5, MovementCounter: new(uint8)},
+	} {
+		if _, moved := tr.Observe(m); moved {
+			t.Errorf("Observe(%+v) moved = true, want false", m)
+		}
+	}
+
+	if _, ok := tr.Total(testMAC); ok {
+		t.Error("Total() = true for untracked readings, want false")
+	}
+}
+
+func TestMovementTracker_ObserveEnvelope(t *testing.T) {
+	tr := NewMovementTracker()
+
+	envelope := func(payload string) *tag.Envelope {
+		t.Helper()
+		data, err := hex.DecodeString(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env, err := tag.NewEnvelope(data)
+		if err != nil {
+			t.Fatalf("NewEnvelope() error = %v", err)
+		}
+		return env
+	}
+
+	// Format 6 has no movement counter, even when keyed by the BLE address
+	air := envelope("06170C5668C79E007000C90501D9FFCD004C884F")
+	air.Address = &otherTestMAC
+	if _, moved := tr.ObserveEnvelope(air); moved {
+		t.Error("ObserveEnvelope(Format 6) moved = true, want false")
+	}
+	if _, ok := tr.Total(otherTestMAC); ok {
+		t.Error("Total() = true for Format 6, want false")
+	}
+
+	// Format 5 with an unavailable MAC address is keyed by the BLE address
+	first := envelope("0512FC5394C37C0004FFFC040CAC364200CDFFFFFFFFFFFF")
+	if _, moved := tr.ObserveEnvelope(first); moved {
+		t.Error("ObserveEnvelope() without address moved = true, want false")
+	}
+	if _, ok := tr.Total(testMAC); ok {
+		t.Error("Total() = true without address, want false")
+	}
+
+	first.Address = &testMAC
+	if _, moved := tr.ObserveEnvelope(first); moved {
+		t.Error("ObserveEnvelope() first reading moved = true, want false")
+	}
+	if _, moved := tr.ObserveEnvelope(first); moved {
+		t.Error("ObserveEnvelope() repeat moved = true, want false")
+	}
+
+	next := envelope("0512FC5394C37C0004FFFC040CAC364500CEFFFFFFFFFFFF")
+	next.Address = &testMAC
+	event, moved := tr.ObserveEnvelope(next)
+	if !moved || event.MAC != testMAC || event.Movements != 3 || event.Total != 3 {
+		t.Errorf("ObserveEnvelope() = %+v, %v; want 3 movements on %s", event, moved, testMAC)
+	}
+
+	if _, moved := tr.ObserveEnvelope(nil); moved {
+		t.Error("ObserveEnvelope(nil) moved = true, want false")
+	}
+}
+
+func TestMovementTracker_PerTag(t *testing.T) {
+	tr := NewMovementTracker()
+	tr.Observe(movementReading(testMAC, 10, seqPtr(1)))
+	tr.Observe(movementReading(otherTestMAC, 200, seqPtr(500)))
+
+	event, moved := tr.Observe(movementReading(testMAC, 12, seqPtr(2)))
+	if !moved || event.MAC != testMAC || event.Total != 2 {
+		t.Errorf("Observe() = %+v, %v; want 2 movements on %s", event, moved, testMAC)
+	}
+	if total, _ := tr.Total(otherTestMAC); total != 0 {
+		t.Errorf("Total(other) = %d, want 0", total)
+	}
+
+	tr.Forget(testMAC)
+	if _, ok := tr.Total(testMAC); ok {
+		t.Error("Total() after Forget = true, want false")
+	}
+	if _, moved := tr.Observe(movementReading(testMAC, 50, seqPtr(3))); moved {
+		t.Error("first reading after Forget moved = true, want false")
+	}
+}
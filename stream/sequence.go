@@ -0,0 +1,263 @@
+// Package stream provides stateful processing of successive RuuviTag
+// measurements, such as deduplication by measurement sequence number and
+// packet loss statistics.
+//
+// Tags broadcast each measurement several times and all trackers in this
+// package are keyed by tag MAC address, so a single tracker can follow any
+// number of tags. Trackers are safe for concurrent use.
+package stream
+
+import (
+	"sync"
+
+	"github.com/marcgeld/ruuvi/common"
+	"github.com/marcgeld/ruuvi/tag"
+)
+
+// staleWindow is the number of sequence numbers below the last one that are
+// treated as late repeats rather than a tag reboot.
+const staleWindow = 16
+
+// SequenceModulus returns the number of distinct measurement sequence numbers
+// of a data format, after which the sequence wraps around to 0.
+// Returns 0 for formats without a measurement sequence.
+func SequenceModulus(format tag.DataFormat) uint32 {
+	switch format {
+	case tag.Format5, tag.Format8, tag.FormatC5:
+		return 65535 // 0-65534, 65535 marks "not available"
+	case tag.Format6:
+		return 256 // 0-255
+	case tag.FormatE1:
+		return 0xFFFFFF // 0-16777214, 0xFFFFFF marks "not available"
+	default:
+		return 0
+	}
+}
+
+// SequenceStatus classifies a measurement by its sequence number.
+type SequenceStatus int
+
+const (
+	// SequenceUntracked means the measurement has no MAC address or sequence
+	// number, or its format has no sequence.
+	SequenceUntracked SequenceStatus = iota
+
+	// SequenceNew is the next measurement of the tag, or its first.
+	SequenceNew
+
+	// SequenceGap is a new measurement after one or more lost measurements.
+	SequenceGap
+
+	// SequenceDuplicate is a repeat of an already received measurement.
+	SequenceDuplicate
+
+	// SequenceReboot is a new measurement after the sequence jumped back,
+	// which happens when the tag restarts.
+	SequenceReboot
+)
+
+// String returns the name of the status.
+func (s SequenceStatus) String() string {
+	switch s {
+	case SequenceUntracked:
+		return "untracked"
+	case SequenceNew:
+		return "new"
+	case SequenceGap:
+		return "gap"
+	case SequenceDuplicate:
+		return "duplicate"
+	case SequenceReboot:
+		return "reboot"
+	default:
+		return "unknown"
+	}
+}
+
+// IsNew reports whether the measurement should be processed, that is it is
+// neither a duplicate nor untracked.
+func (s SequenceStatus) IsNew() bool {
+	return s == SequenceNew || s == SequenceGap || s == SequenceReboot
+}
+
+// SequenceResult is the outcome of observing a measurement.
+type SequenceResult struct {
+	Status SequenceStatus
+	Lost   uint32 // Number of measurements missed before this one, for SequenceGap
+}
+
+// SequenceStats holds the counters of one tag.
+type SequenceStats struct {
+	Received     uint64 // Unique measurements received
+	Duplicates   uint64 // Repeated measurements dropped
+	Lost         uint64 // Measurements missed according to sequence gaps
+	Reboots      uint64 // Sequence resets
+	LastSequence uint32 // Sequence number of the last unique measurement
+}
+
+// LossRate returns the fraction of measurements lost, between 0 and 1.
+func (s SequenceStats) LossRate() float64 {
+	total := s.Received + s.Lost
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Lost) / float64(total)
+}
+
+// SequenceTracker deduplicates measurements by their sequence number and
+// counts received, duplicate and lost measurements per tag.
+//
+// A step back of up to 16 sequence numbers is taken as a late repeat; a
+// larger step back as a reboot. A jump from the lower three quarters of the
+// sequence range back to one of the first 16 sequence numbers is a reboot
+// too; from the top quarter it is taken as a wraparound, possibly with lost
+// measurements. A tag that stays out of range for more than half of the
+// sequence range is therefore also reported as rebooted.
+type SequenceTracker struct {
+	mu   sync.Mutex
+	tags map[common.MACAddress]*SequenceStats
+}
+
+// NewSequenceTracker returns an empty SequenceTracker.
+func NewSequenceTracker() *SequenceTracker {
+	return &SequenceTracker{tags: make(map[common.MACAddress]*SequenceStats)}
+}
+
+// ObserveMeasurement observes a decoded measurement. Measurements without a
+// MAC address or sequence number are SequenceUntracked; use Observe with the
+// Bluetooth address for formats that do not carry the full MAC address.
+func (t *SequenceTracker) ObserveMeasurement(m *tag.Measurement) SequenceResult {
+	if m == nil || m.MACAddress == nil || m.MeasurementSequence == nil {
+		return SequenceResult{Status: SequenceUntracked}
+	}
+	return t.Observe(*m.MACAddress, m.Format, *m.MeasurementSequence)
+}
+
+// ObserveEnvelope observes a received measurement, keyed by the MAC address
+// returned by tag.EnvelopeMAC. This tracks formats that do not carry the full
+// MAC address by their Bluetooth address.
+func (t *SequenceTracker) ObserveEnvelope(e *tag.Envelope) SequenceResult {
+	mac, ok := tag.EnvelopeMAC(e)
+	if !ok || e.Data == nil {
+		return SequenceResult{Status: SequenceUntracked}
+	}
+
+	m := e.Data.Measurement()
+	if m == nil || m.MeasurementSequence == nil {
+		return SequenceResult{Status: SequenceUntracked}
+	}
+	return t.Observe(mac, m.Format, *m.MeasurementSequence)
+}
+
+// Observe observes the measurement with sequence number seq of the tag with
+// the given MAC address and data format.
+func (t *SequenceTracker) Observe(mac common.MACAddress, format tag.DataFormat, seq uint32) SequenceResult {
+	modulus := SequenceModulus(format)
+	if modulus == 0 || seq >= modulus {
+		return SequenceResult{Status: SequenceUntracked}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats, ok := t.tags[mac]
+	if !ok {
+		t.tags[mac] = &SequenceStats{Received: 1, LastSequence: seq}
+		return SequenceResult{Status: SequenceNew}
+	}
+
+	status, ahead := classifySequence(modulus, stats.LastSequence, seq)
+	switch status {
+	case SequenceDuplicate:
+		stats.Duplicates++
+		return SequenceResult{Status: SequenceDuplicate}
+
+	case SequenceReboot:
+		stats.Received++
+		stats.Reboots++
+		stats.LastSequence = seq
+		return SequenceResult{Status: SequenceReboot}
+
+	default:
+		stats.Received++
+		stats.LastSequence = seq
+		if status == SequenceNew {
+			return SequenceResult{Status: SequenceNew}
+		}
+		stats.Lost += uint64(ahead - 1)
+		return SequenceResult{Status: SequenceGap, Lost: ahead - 1}
+	}
+}
+
+// ClassifySequence classifies the measurement sequence number seq of a tag
+// with the given data format following last, by the rules of
+// SequenceTracker but without keeping any state. Returns SequenceUntracked
+// for formats without a sequence and for sequence numbers out of range.
+func ClassifySequence(format tag.DataFormat, last, seq uint32) SequenceStatus {
+	modulus := SequenceModulus(format)
+	if modulus == 0 || last >= modulus || seq >= modulus {
+		return SequenceUntracked
+	}
+	status, _ := classifySequence(modulus, last, seq)
+	return status
+}
+
+// classifySequence classifies sequence number seq following last in a
+// sequence of the given modulus. It also returns the number of steps seq is
+// ahead of last.
+func classifySequence(modulus, last, seq uint32) (SequenceStatus, uint32) {
+	ahead := (seq + modulus - last) % modulus
+	switch {
+	case ahead == 0:
+		return SequenceDuplicate, ahead
+
+	case seq < last && seq < staleWindow && ahead > staleWindow && last < modulus-modulus/4:
+		// A restarted tag counts from 0 again; a wraparound, even one with
+		// lost measurements, comes from the top of the range.
+		return SequenceReboot, ahead
+
+	case ahead == 1:
+		return SequenceNew, ahead
+
+	case ahead <= modulus/2:
+		return SequenceGap, ahead
+
+	case modulus-ahead <= staleWindow:
+		return SequenceDuplicate, ahead
+
+	default:
+		return SequenceReboot, ahead
+	}
+}
+
+// Stats returns the counters of the tag with the given MAC address.
+// Returns false if the tag has not been seen.
+func (t *SequenceTracker) Stats(mac common.MACAddress) (SequenceStats, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats, ok := t.tags[mac]
+	if !ok {
+		return SequenceStats{}, false
+	}
+	return *stats, true
+}
+
+// AllStats returns the counters of all tags seen.
+func (t *SequenceTracker) AllStats() map[common.MACAddress]SequenceStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	all := make(map[common.MACAddress]SequenceStats, len(t.tags))
+	for mac, stats := range t.tags {
+		all[mac] = *stats
+	}
+	return all
+}
+
+// Forget drops the state and counters of the tag with the given MAC address.
+func (t *SequenceTracker) Forget(mac common.MACAddress) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.tags, mac)
+}
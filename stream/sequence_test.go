@@ -0,0 +1,234 @@
+package stream
+
+import (
+	"encoding/hex"
+	"sync"
+	"testing"
+
+	"github.com/marcgeld/ruuvi/common"
+	"github.com/marcgeld/ruuvi/tag"
+)
+
+var (
+	testMAC      = common.MACAddress{0xCB, 0xB8, 0x33, 0x4C, 0x88, 0x4F}
+	otherTestMAC = common.MACAddress{0xCB, 0xB8, 0x33, 0x4C, 0x88, 0x50}
+)
+
+func TestSequenceModulus(t *testing.T) {
+	tests := []struct {
+		format tag.DataFormat
+		want   uint32
+	}{
+		{tag.Format2, 0},
+		{tag.Format3, 0},
+		{tag.Format5, 65535},
+		{tag.Format6, 256},
+		{tag.Format8, 65535},
+		{tag.FormatC5, 65535},
+		{tag.FormatE1, 16777215},
+	}
+
+	for _, tt := range tests {
+		if got := SequenceModulus(tt.format); got != tt.want {
+			t.Errorf("SequenceModulus(%s) = %d, want %d", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestSequenceTracker_Observe(t *testing.T) {
+	tr := NewSequenceTracker()
+
+	steps := []struct {
+		name   string
+		format tag.DataFormat
+		seq    uint32
+		status SequenceStatus
+		lost   uint32
+	}{
+		{name: "first", format: tag.Format5, seq: 100, status: SequenceNew},
+		{name: "repeat", format: tag.Format5, seq: 100, status: SequenceDuplicate},
+		{name: "repeat again", format: tag.Format5, seq: 100, status: SequenceDuplicate},
+		{name: "next", format: tag.Format5, seq: 101, status: SequenceNew},
+		{name: "gap", format: tag.Format5, seq: 105, status: SequenceGap, lost: 3},
+		{name: "late repeat", format: tag.Format5, seq: 103, status: SequenceDuplicate},
+		{name: "reboot", format: tag.Format5, seq: 0, status: SequenceReboot},
+		{name: "after reboot", format: tag.Format5, seq: 1, status: SequenceNew},
+		{name: "invalid sequence", format: tag.Format5, seq: 65535, status: SequenceUntracked},
+		{name: "format without sequence", format: tag.Format3, seq: 2, status: SequenceUntracked},
+	}
+
+	for _, step := range steps {
+		got := tr.Observe(testMAC, step.format, step.seq)
+		if got.Status != step.status || got.Lost != step.lost {
+			t.Errorf("%s: Observe(%d) = %+v, want {%s %d}", step.name, step.seq, got, step.status, step.lost)
+		}
+	}
+
+	stats, ok := tr.Stats(testMAC)
+	if !ok {
+		t.Fatal("Stats() = false, want true")
+	}
+	want := SequenceStats{Received: 5, Duplicates: 3, Lost: 3, Reboots: 1, LastSequence: 1}
+	if stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+	if rate := stats.LossRate(); rate != 3.0/8.0 {
+		t.Errorf("LossRate() = %v, want 0.375", rate)
+	}
+}
+
+func TestSequenceTracker_Wraparound(t *testing.T) {
+	tests := []struct {
+		name   string
+		format tag.DataFormat
+		last   uint32
+		next   uint32
+		status SequenceStatus
+		lost   uint32
+	}{
+		{name: "format 5", format: tag.Format5, last: 65534, next: 0, status: SequenceNew},
+		{name: "format 5 gap", format: tag.Format5, last: 65533, next: 1, status: SequenceGap, lost: 2},
+		{name: "format 6", format: tag.Format6, last: 255, next: 0, status: SequenceNew},
+		{name: "format E1", format: tag.FormatE1, last: 0xFFFFFE, next: 0, status: SequenceNew},
+		{name: "late repeat across wrap", format: tag.Format5, last: 2, next: 65530, status: SequenceDuplicate},
+		{name: "format 5 reboot from high sequence", format: tag.Format5, last: 40000, next: 0, status: SequenceReboot},
+		{name: "format 6 reboot from high sequence", format: tag.Format6, last: 150, next: 0, status: SequenceReboot},
+		{name: "format 5 wraparound with large gap", format: tag.Format5, last: 65530, next: 12, status: SequenceGap, lost: 16},
+		{name: "format 6 wraparound with large gap", format: tag.Format6, last: 240, next: 5, status: SequenceGap, lost: 20},
+		{name: "format 6 wraparound from top quarter", format: tag.Format6, last: 200, next: 3, status: SequenceGap, lost: 58},
+		{name: "format E1 wraparound with large gap", format: tag.FormatE1, last: 0xFFFFF0, next: 9, status: SequenceGap, lost: 23},
+		{name: "format E1 reboot from high sequence", format: tag.FormatE1, last: 9000000, next: 3, status: SequenceReboot},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewSequenceTracker()
+			tr.Observe(testMAC, tt.format, tt.last)
+			got := tr.Observe(testMAC, tt.format, tt.next)
+			if got.Status != tt.status || got.Lost != tt.lost {
+				t.Errorf("Observe(%d) after %d = %+v, want {%s %d}", tt.next, tt.last, got, tt.status, tt.lost)
+			}
+		})
+	}
+}
+
+func TestClassifySequence(t *testing.T) {
+	tests := []struct {
+		format    tag.DataFormat
+		last, seq uint32
+		want      SequenceStatus
+	}{
+		{tag.Format5, 100, 101, SequenceNew},
+		{tag.Format5, 100, 105, SequenceGap},
+		{tag.Format5, 100, 100, SequenceDuplicate},
+		{tag.Format5, 100, 95, SequenceDuplicate},
+		{tag.Format5, 65534, 0, SequenceNew},
+		{tag.Format5, 40000, 0, SequenceReboot},
+		{tag.Format6, 250, 2, SequenceGap},
+		{tag.Format5, 100, 65535, SequenceUntracked},
+		{tag.Format3, 1, 2, SequenceUntracked},
+	}
+
+	for _, tt := range tests {
+		if got := ClassifySequence(tt.format, tt.last, tt.seq); got != tt.want {
+			t.Errorf("ClassifySequence(%s, %d, %d) = %s, want %s", tt.format, tt.last, tt.seq, got, tt.want)
+		}
+	}
+}
+
+func TestSequenceTracker_ObserveMeasurement(t *testing.T) {
+	tr := NewSequenceTracker()
+
+	seq := uint32(205)
+	m := &tag.Measurement{Format: tag.Format5, MACAddress: &testMAC, MeasurementSequence: &seq}
+	if got := tr.ObserveMeasurement(m); got.Status != SequenceNew || !got.Status.IsNew() {
+		t.Errorf("ObserveMeasurement() = %+v, want new", got)
+	}
+	if got := tr.ObserveMeasurement(m); got.Status != SequenceDuplicate || got.Status.IsNew() {
+		t.Errorf("ObserveMeasurement() = %+v, want duplicate", got)
+	}
+
+	for _, m := range []*tag.Measurement{
+		nil,
+		{Format: tag.Format5, MeasurementSequence: &seq},
+		{Format: tag.Format5, MACAddress: &testMAC},
+	} {
+		if got := tr.ObserveMeasurement(m); got.Status != SequenceUntracked {
+			t.Errorf("ObserveMeasurement(%+v) = %+v, want untracked", m, got)
+		}
+	}
+}
+
+func TestSequenceTracker_ObserveEnvelope(t *testing.T) {
+	tr := NewSequenceTracker()
+
+	data, err := hex.DecodeString("06170C5668C79E007000C90501D9FFCD004C884F")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := tag.NewEnvelope(data)
+	if err != nil {
+		t.Fatalf("NewEnvelope() error = %v", err)
+	}
+
+	// Format 6 only carries a MAC suffix, so it is untracked without the BLE address
+	if got := tr.ObserveEnvelope(env); got.Status != SequenceUntracked {
+		t.Errorf("ObserveEnvelope() without address = %+v, want untracked", got)
+	}
+
+	env.Address = &testMAC
+	if got := tr.ObserveEnvelope(env); got.Status != SequenceNew {
+		t.Errorf("ObserveEnvelope() = %+v, want new", got)
+	}
+	if got := tr.ObserveEnvelope(env); got.Status != SequenceDuplicate {
+		t.Errorf("ObserveEnvelope() = %+v, want duplicate", got)
+	}
+	if stats, ok := tr.Stats(testMAC); !ok || stats.Received != 1 {
+		t.Errorf("Stats() = %+v, %v", stats, ok)
+	}
+
+	if got := tr.ObserveEnvelope(nil); got.Status != SequenceUntracked {
+		t.Errorf("ObserveEnvelope(nil) = %+v, want untracked", got)
+	}
+}
+
+func TestSequenceTracker_PerTag(t *testing.T) {
+	tr := NewSequenceTracker()
+	tr.Observe(testMAC, tag.Format5, 10)
+	tr.Observe(otherTestMAC, tag.Format5, 10)
+
+	if got := tr.Observe(otherTestMAC, tag.Format5, 11); got.Status != SequenceNew {
+		t.Errorf("Observe() = %+v, want new", got)
+	}
+
+	all := tr.AllStats()
+	if len(all) != 2 || all[testMAC].Received != 1 || all[otherTestMAC].Received != 2 {
+		t.Errorf("AllStats() = %+v", all)
+	}
+
+	tr.Forget(testMAC)
+	if _, ok := tr.Stats(testMAC); ok {
+		t.Error("Stats() after Forget = true, want false")
+	}
+}
+
+func TestSequenceTracker_Concurrent(t *testing.T) {
+	tr := NewSequenceTracker()
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for seq := range uint32(100) {
+				tr.Observe(testMAC, tag.Format5, seq)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats, _ := tr.Stats(testMAC)
+	if stats.Received+stats.Duplicates != 800 {
+		t.Errorf("Received + Duplicates = %d, want 800", stats.Received+stats.Duplicates)
+	}
+}
@@ -0,0 +1,139 @@
+package tag
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// RuuviCompanyID is the Bluetooth SIG company identifier of Ruuvi Innovations Ltd.
+const RuuviCompanyID uint16 = 0x0499
+
+// Bluetooth advertising data (AD) types used by Ruuvi devices.
+const (
+	adTypeFlags             = 0x01
+	adTypeShortLocalName    = 0x08
+	adTypeCompleteLocalName = 0x09
+	adTypeTxPower           = 0x0A
+	adTypeServiceData16     = 0x16
+	adTypeManufacturerData  = 0xFF
+)
+
+// Advertisement represents a parsed Bluetooth LE advertisement from a Ruuvi device.
+type Advertisement struct {
+	Flags            *uint8       // Flags AD field
+	LocalName        string       // Complete or shortened local name, empty if absent
+	TxPower          *int8        // TX power level AD field in dBm
+	ManufacturerData []byte       // Ruuvi payload from manufacturer specific data, without company ID
+	URL              string       // Expanded Eddystone-URL, empty if absent
+	Data             *DecodedData // Decoded Ruuvi sensor data
+}
+
+// ParseAdvertisement parses a Bluetooth LE advertising payload made of AD
+// structures (length, type, value) and decodes the Ruuvi sensor data it carries.
+//
+// Sensor data is taken from manufacturer specific data with company ID 0x0499,
+// or from an Eddystone-URL frame pointing to ruu.vi for Formats 2 and 4.
+// Flags, local name and TX power AD fields are returned alongside.
+//
+// Returns ErrMalformedAdvertisement if an AD structure overruns the payload and
+// ErrNoRuuviData if no Ruuvi sensor data is present.
+func ParseAdvertisement(pdu []byte) (*Advertisement, error) {
+	adv := &Advertisement{}
+	var payload []byte
+
+	for offset := 0; offset < len(pdu); {
+		length := int(pdu[offset])
+		if length == 0 {
+			// Zero length marks the end of the significant part
+			break
+		}
+
+		end := offset + 1 + length
+		if end > len(pdu) {
+			return nil, fmt.Errorf("%w: AD structure at offset %d needs %d bytes, %d left",
+				ErrMalformedAdvertisement, offset, length, len(pdu)-offset-1)
+		}
+
+		adType := pdu[offset+1]
+		value := pdu[offset+2 : end]
+		offset = end
+
+		switch adType {
+		case adTypeFlags:
+			if len(value) >= 1 {
+				flags := value[0]
+				adv.Flags = &flags
+			}
+
+		case adTypeShortLocalName:
+			if adv.LocalName == "" {
+				adv.LocalName = string(value)
+			}
+
+		case adTypeCompleteLocalName:
+			adv.LocalName = string(value)
+
+		case adTypeTxPower:
+			if len(value) >= 1 {
+				tx := int8(value[0])
+				adv.TxPower = &tx
+			}
+
+		case adTypeManufacturerData:
+			// A Ruuvi company ID without a payload carries no sensor data
+			if payload != nil || len(value) <= 2 || binary.LittleEndian.Uint16(value) != RuuviCompanyID {
+				continue
+			}
+			adv.ManufacturerData = value[2:]
+			payload = adv.ManufacturerData
+
+		case adTypeServiceData16:
+			if len(value) < 2 || binary.LittleEndian.Uint16(value) != eddystoneUUID {
+				continue
+			}
+			url, _, err := expandEddystoneURL(value[2:])
+			if err != nil {
+				continue // Other Eddystone frame types
+			}
+			adv.URL = url
+			if payload == nil {
+				if raw, err := decodeRuuviURL(url); err == nil {
+					payload = raw
+				}
+			}
+		}
+	}
+
+	if payload == nil {
+		return nil, ErrNoRuuviData
+	}
+
+	decoded, err := Decode(payload)
+	if err != nil {
+		return nil, err
+	}
+	adv.Data = decoded
+
+	return adv, nil
+}
+
+// DecodeManufacturerSpecificData decodes the value of a manufacturer specific
+// data AD field: the little-endian company ID 0x0499 followed by the Ruuvi payload.
+// It is the inverse of EncodeFormat5ManufacturerData.
+func DecodeManufacturerSpecificData(data []byte) (*DecodedData, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("%w: manufacturer data requires at least 2 bytes, got %d",
+			ErrMalformedAdvertisement, len(data))
+	}
+
+	if id := binary.LittleEndian.Uint16(data); id != RuuviCompanyID {
+		return nil, fmt.Errorf("%w: company ID is 0x%04X", ErrNoRuuviData, id)
+	}
+
+	payload := data[2:]
+	if len(payload) == 0 {
+		return nil, fmt.Errorf("%w: manufacturer data holds only the company ID", ErrNoRuuviData)
+	}
+
+	return Decode(payload)
+}
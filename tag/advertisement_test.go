@@ -0,0 +1,127 @@
+package tag
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// buildAD concatenates AD structures built from type/value pairs.
+func buildAD(structures ...[]byte) []byte {
+	var pdu []byte
+	for _, s := range structures {
+		pdu = append(pdu, byte(len(s)))
+		pdu = append(pdu, s...)
+	}
+	return pdu
+}
+
+func TestParseAdvertisement_ManufacturerData(t *testing.T) {
+	payload, err := hex.DecodeString("0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F")
+	if err != nil {
+		t.Fatalf("Failed to decode hex: %v", err)
+	}
+
+	pdu := buildAD(
+		[]byte{adTypeFlags, 0x06},
+		append([]byte{adTypeManufacturerData, 0x99, 0x04}, payload...),
+		append([]byte{adTypeCompleteLocalName}, "Ruuvi 884F"...),
+		[]byte{adTypeTxPower, 0x04},
+	)
+
+	adv, err := ParseAdvertisement(pdu)
+	if err != nil {
+		t.Fatalf("ParseAdvertisement failed: %v", err)
+	}
+
+	if adv.Flags == nil || *adv.Flags != 0x06 {
+		t.Errorf("Flags = %v, want 0x06", adv.Flags)
+	}
+	if adv.LocalName != "Ruuvi 884F" {
+		t.Errorf("LocalName = %q, want %q", adv.LocalName, "Ruuvi 884F")
+	}
+	if adv.TxPower == nil || *adv.TxPower != 4 {
+		t.Errorf("TxPower = %v, want 4", adv.TxPower)
+	}
+	if !bytesEqual(adv.ManufacturerData, payload) {
+		t.Errorf("ManufacturerData = %X, want %X", adv.ManufacturerData, payload)
+	}
+	if adv.Data == nil || adv.Data.Format != Format5 || adv.Data.Format5 == nil {
+		t.Fatalf("Data = %+v, want Format 5 data", adv.Data)
+	}
+}
+
+func TestParseAdvertisement_EddystoneURL(t *testing.T) {
+	frame := append([]byte{adTypeServiceData16, 0xAA, 0xFE, eddystoneURLFrame, 0xC4, 0x03}, "ruu.vi/#BEgAAMQ4T"...)
+	pdu := buildAD(
+		[]byte{adTypeFlags, 0x06},
+		[]byte{0x03, 0xAA, 0xFE}, // Complete list of 16-bit service UUIDs
+		frame,
+	)
+
+	adv, err := ParseAdvertisement(pdu)
+	if err != nil {
+		t.Fatalf("ParseAdvertisement failed: %v", err)
+	}
+
+	if adv.URL != "https://ruu.vi/#BEgAAMQ4T" {
+		t.Errorf("URL = %q, want https://ruu.vi/#BEgAAMQ4T", adv.URL)
+	}
+	if adv.Data == nil || adv.Data.Format != Format4 || adv.Data.Format4 == nil {
+		t.Fatalf("Data = %+v, want Format 4 data", adv.Data)
+	}
+	if h := adv.Data.Format4.Humidity; h == nil || *h != 36 {
+		t.Errorf("Humidity = %v, want 36", h)
+	}
+	if p := adv.Data.Format4.Pressure; p == nil || *p != 100232 {
+		t.Errorf("Pressure = %v, want 100232", p)
+	}
+}
+
+func TestParseAdvertisement_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		pdu  []byte
+		want error
+	}{
+		{name: "empty", pdu: nil, want: ErrNoRuuviData},
+		{name: "overrun", pdu: []byte{0x05, adTypeFlags, 0x06}, want: ErrMalformedAdvertisement},
+		{name: "other company", pdu: buildAD([]byte{adTypeManufacturerData, 0x4C, 0x00, 0x02, 0x15}), want: ErrNoRuuviData},
+		{name: "company ID only", pdu: buildAD([]byte{adTypeManufacturerData, 0x99, 0x04}), want: ErrNoRuuviData},
+		{name: "unknown format", pdu: buildAD([]byte{adTypeManufacturerData, 0x99, 0x04, 0xFF}), want: ErrUnknownFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseAdvertisement(tt.pdu); !errors.Is(err, tt.want) {
+				t.Errorf("ParseAdvertisement() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeManufacturerSpecificData_RoundTrip(t *testing.T) {
+	temp := 21.5
+	data, err := EncodeFormat5ManufacturerData(&Format5Data{Temperature: &temp})
+	if err != nil {
+		t.Fatalf("EncodeFormat5ManufacturerData failed: %v", err)
+	}
+
+	decoded, err := DecodeManufacturerSpecificData(data)
+	if err != nil {
+		t.Fatalf("DecodeManufacturerSpecificData failed: %v", err)
+	}
+	if decoded.Format5 == nil || decoded.Format5.Temperature == nil || *decoded.Format5.Temperature != temp {
+		t.Errorf("decoded = %+v, want temperature %v", decoded.Format5, temp)
+	}
+
+	if _, err := DecodeManufacturerSpecificData([]byte{0x4C, 0x00, 0x05}); !errors.Is(err, ErrNoRuuviData) {
+		t.Errorf("other company error = %v, want ErrNoRuuviData", err)
+	}
+	if _, err := DecodeManufacturerSpecificData([]byte{0x99, 0x04}); !errors.Is(err, ErrNoRuuviData) || errors.Is(err, ErrEmpty) {
+		t.Errorf("company ID only error = %v, want ErrNoRuuviData", err)
+	}
+	if _, err := DecodeManufacturerSpecificData([]byte{0x99}); !errors.Is(err, ErrMalformedAdvertisement) {
+		t.Errorf("short data error = %v, want ErrMalformedAdvertisement", err)
+	}
+}
@@ -0,0 +1,524 @@
+package tag
+
+import (
+	"math"
+
+	"github.com/marcgeld/ruuvi/common"
+)
+
+// binding ties a Field to the member of the data struct T that holds its
+// value. Bindings without decode and encode functions, such as the format
+// byte and reserved bytes, are skipped when decoding and filled with fill
+// when encoding.
+type binding[T any] struct {
+	Field
+	fill   byte
+	decode func(data []byte, v *T)
+	encode func(b []byte, v *T, opts EncodeOptions) error
+}
+
+// formatCodec decodes and encodes a data format field by field.
+type formatCodec[T any] struct {
+	format   DataFormat
+	size     int // Payload length in bytes, including the format byte
+	bindings []binding[T]
+}
+
+// layout returns the fields of the codec.
+func (c *formatCodec[T]) layout() formatLayout {
+	fields := make([]Field, len(c.bindings))
+	for i := range c.bindings {
+		fields[i] = c.bindings[i].Field
+	}
+	return formatLayout{size: c.size, fields: fields}
+}
+
+// validate checks the length and format byte of data.
+func (c *formatCodec[T]) validate(data []byte) error {
+	if len(data) != c.size {
+		return &LengthError{Format: c.format, Want: c.size, Got: len(data)}
+	}
+
+	if DataFormat(data[0]) != c.format {
+		return &FormatMismatchError{Want: c.format, Got: DataFormat(data[0])}
+	}
+
+	return nil
+}
+
+// decode sets the members of v from data, which must have been validated.
+func (c *formatCodec[T]) decode(data []byte, v *T) {
+	for i := range c.bindings {
+		if c.bindings[i].decode != nil {
+			c.bindings[i].decode(data, v)
+		}
+	}
+}
+
+// encode returns the payload holding the members of v.
+func (c *formatCodec[T]) encode(v *T, opts EncodeOptions) ([]byte, error) {
+	if v == nil {
+		return nil, ErrNilData
+	}
+
+	b := make([]byte, c.size)
+	for i := range c.bindings {
+		bd := &c.bindings[i]
+		if bd.encode == nil {
+			for j := bd.Offset; j < bd.Offset+bd.Size; j++ {
+				b[j] = bd.fill
+			}
+			continue
+		}
+		if err := bd.encode(b, v, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+// floatField binds f to a *float64 member of T. NaN is encoded as "not available".
+func floatField[T any](f Field, member func(*T) **float64) binding[T] {
+	r := newFieldReader(f)
+	return binding[T]{
+		Field: f,
+		decode: func(data []byte, v *T) {
+			raw := r.raw(data)
+			if f.notAvailable(data, raw) {
+				*member(v) = nil
+				return
+			}
+			value := r.value(data, raw)
+			*member(v) = &value
+		},
+		encode: func(b []byte, v *T, opts EncodeOptions) error {
+			p := *member(v)
+			if p == nil || math.IsNaN(*p) {
+				f.putSentinel(b)
+				return nil
+			}
+			return f.put(b, *p, opts)
+		},
+	}
+}
+
+// integer is the set of member types of integer fields.
+type integer interface {
+	~int | ~uint8 | ~uint16 | ~uint32
+}
+
+// intField binds f to an integer pointer member of T.
+func intField[T any, V integer](f Field, member func(*T) **V) binding[T] {
+	r := newFieldReader(f)
+	return binding[T]{
+		Field: f,
+		decode: func(data []byte, v *T) {
+			raw := r.raw(data)
+			if f.notAvailable(data, raw) {
+				*member(v) = nil
+				return
+			}
+			value := V(r.value(data, raw))
+			*member(v) = &value
+		},
+		encode: func(b []byte, v *T, opts EncodeOptions) error {
+			p := *member(v)
+			if p == nil {
+				f.putSentinel(b)
+				return nil
+			}
+			return f.put(b, float64(*p), opts)
+		},
+	}
+}
+
+// flagsField binds a FieldFlags field to a uint8 member of T.
+func flagsField[T any](f Field, member func(*T) *uint8) binding[T] {
+	return binding[T]{
+		Field: f,
+		decode: func(data []byte, v *T) {
+			*member(v) = data[f.Offset] &^ f.LSBMask
+		},
+		encode: func(b []byte, v *T, _ EncodeOptions) error {
+			// The LSBs may already have been set by their fields
+			b[f.Offset] = b[f.Offset]&f.LSBMask | *member(v)&^f.LSBMask
+			return nil
+		},
+	}
+}
+
+// macField binds a 6-byte FieldMAC field to a *common.MACAddress member of T.
+func macField[T any](f Field, member func(*T) **common.MACAddress) binding[T] {
+	return binding[T]{
+		Field: f,
+		decode: func(data []byte, v *T) {
+			if f.notAvailable(data, 0) {
+				*member(v) = nil
+				return
+			}
+			var mac common.MACAddress
+			copy(mac[:], data[f.Offset:f.Offset+f.Size])
+			*member(v) = &mac
+		},
+		encode: func(b []byte, v *T, _ EncodeOptions) error {
+			var mac []byte
+			if p := *member(v); p != nil {
+				mac = p[:]
+			}
+			putMAC(b[f.Offset:f.Offset+f.Size], mac)
+			return nil
+		},
+	}
+}
+
+// macSuffixField binds a 3-byte FieldMAC field to a *[3]byte member of T.
+func macSuffixField[T any](f Field, member func(*T) **[3]byte) binding[T] {
+	return binding[T]{
+		Field: f,
+		decode: func(data []byte, v *T) {
+			if f.notAvailable(data, 0) {
+				*member(v) = nil
+				return
+			}
+			var suffix [3]byte
+			copy(suffix[:], data[f.Offset:f.Offset+f.Size])
+			*member(v) = &suffix
+		},
+		encode: func(b []byte, v *T, _ EncodeOptions) error {
+			var mac []byte
+			if p := *member(v); p != nil {
+				mac = p[:]
+			}
+			putMAC(b[f.Offset:f.Offset+f.Size], mac)
+			return nil
+		},
+	}
+}
+
+// valueField binds f to a value member of T whose availability is tracked by
+// bit in the bitmask returned by valid. Not available values are zero.
+func valueField[T any, V integer | ~float64, M ~uint16](f Field, bit M, valid func(*T) *M, member func(*T) *V) binding[T] {
+	r := newFieldReader(f)
+	return binding[T]{
+		Field: f,
+		decode: func(data []byte, v *T) {
+			raw := r.raw(data)
+			if f.notAvailable(data, raw) {
+				*member(v) = 0
+				*valid(v) &^= bit
+				return
+			}
+			*member(v) = V(r.value(data, raw))
+			*valid(v) |= bit
+		},
+		encode: func(b []byte, v *T, opts EncodeOptions) error {
+			value := float64(*member(v))
+			if *valid(v)&bit == 0 || math.IsNaN(value) {
+				f.putSentinel(b)
+				return nil
+			}
+			return f.put(b, value, opts)
+		},
+	}
+}
+
+// valueMACField binds a 6-byte FieldMAC field to a common.MACAddress member
+// of T whose availability is tracked by bit in the bitmask returned by valid.
+func valueMACField[T any, M ~uint16](f Field, bit M, valid func(*T) *M, member func(*T) *common.MACAddress) binding[T] {
+	return binding[T]{
+		Field: f,
+		decode: func(data []byte, v *T) {
+			if f.notAvailable(data, 0) {
+				*member(v) = common.MACAddress{}
+				*valid(v) &^= bit
+				return
+			}
+			copy(member(v)[:], data[f.Offset:f.Offset+f.Size])
+			*valid(v) |= bit
+		},
+		encode: func(b []byte, v *T, _ EncodeOptions) error {
+			var mac []byte
+			if *valid(v)&bit != 0 {
+				mac = member(v)[:]
+			}
+			putMAC(b[f.Offset:f.Offset+f.Size], mac)
+			return nil
+		},
+	}
+}
+
+// fieldReader decodes the raw and physical values of a numeric field. The
+// quantities derived from the layout are worked out once, when the codec is
+// built, rather than for every payload.
+type fieldReader struct {
+	f       Field
+	width   uint    // See Field.width
+	lsb     bool    // See Field.hasLSB
+	linear  bool    // Kind is FieldLinear
+	divisor float64 // Inverse of a decimal Scale such as 0.005, 0 otherwise
+}
+
+func newFieldReader(f Field) fieldReader {
+	r := fieldReader{f: f, width: f.width(), lsb: f.hasLSB(), linear: f.Kind == FieldLinear}
+	if d := 1 / f.Scale; f.Scale > 0 && f.Scale < 1 && d == math.Round(d) {
+		r.divisor = d
+	}
+	return r
+}
+
+// raw returns the raw integer value of the field in data.
+func (r *fieldReader) raw(data []byte) int64 {
+	f := &r.f
+	var u uint64
+	switch b := data[f.Offset : f.Offset+f.Size]; len(b) {
+	case 1:
+		u = uint64(b[0])
+	case 2:
+		u = uint64(b[0])<<8 | uint64(b[1])
+	default:
+		for _, x := range b {
+			u = u<<8 | uint64(x)
+		}
+	}
+	u >>= f.Shift
+
+	if f.Bits > 0 {
+		u &= 1<<f.Bits - 1
+	}
+
+	if r.lsb {
+		u <<= 1
+		if data[f.LSBOffset]&f.LSBMask != 0 {
+			u |= 1
+		}
+	}
+
+	if f.Signed && u&(1<<(r.width-1)) != 0 {
+		return int64(u) - 1<<r.width
+	}
+	return int64(u)
+}
+
+// value returns the physical value of the field holding raw.
+func (r *fieldReader) value(data []byte, raw int64) float64 {
+	if r.linear {
+		return r.scaled(raw)
+	}
+	return r.f.float(data, raw)
+}
+
+// scaled returns raw*Scale + ValueOffset. Decimal scales such as 0.005 are
+// applied by dividing by their inverse, which gives the float64 closest to
+// the decimal value.
+func (r *fieldReader) scaled(raw int64) float64 {
+	if r.divisor != 0 {
+		return float64(raw)/r.divisor + r.f.ValueOffset
+	}
+	return float64(raw)*r.f.Scale + r.f.ValueOffset
+}
+
+// putMAC copies mac into b, or fills b with 0xFF when mac is nil.
+func putMAC(b, mac []byte) {
+	if mac == nil {
+		for i := range b {
+			b[i] = 0xFF
+		}
+		return
+	}
+	copy(b, mac)
+}
+
+// width returns the number of bits of the raw value, including a separately
+// stored LSB.
+func (f *Field) width() uint {
+	width := uint(f.Size * 8)
+	if f.Bits > 0 {
+		width = f.Bits
+	}
+	if f.hasLSB() {
+		width++
+	}
+	return width
+}
+
+// hasLSB reports whether the least significant bit is stored apart from the
+// other bits of the field.
+func (f *Field) hasLSB() bool {
+	return f.LSBMask != 0 && f.Kind != FieldFlags
+}
+
+// rawValue returns the raw integer value of the field in data.
+func (f *Field) rawValue(data []byte) int64 {
+	r := newFieldReader(*f)
+	return r.raw(data)
+}
+
+// notAvailable reports whether the field holds its "not available" sentinel.
+func (f *Field) notAvailable(data []byte, raw int64) bool {
+	if f.Kind == FieldMAC {
+		for _, b := range data[f.Offset : f.Offset+f.Size] {
+			if b != 0xFF {
+				return false
+			}
+		}
+		return true
+	}
+	return f.HasSentinel && raw == f.Sentinel
+}
+
+// float returns the physical value of a numeric field.
+func (f *Field) float(data []byte, raw int64) float64 {
+	switch f.Kind {
+	case FieldSignMagnitude:
+		b := data[f.Offset : f.Offset+f.Size]
+		v := float64(b[0] & 0x7F)
+		if f.Size > 1 {
+			v += float64(b[1]) * 0.01
+		}
+		if b[0]&0x80 != 0 {
+			v = -v
+		}
+		return v
+
+	case FieldLogarithmic:
+		return math.Exp(float64(raw)*f.Scale) - 1
+
+	case FieldFlags:
+		return float64(uint8(raw) &^ f.LSBMask)
+
+	default:
+		return f.scaled(raw)
+	}
+}
+
+// scaled returns raw*Scale + ValueOffset, see fieldReader.scaled.
+func (f *Field) scaled(raw int64) float64 {
+	r := newFieldReader(*f)
+	return r.scaled(raw)
+}
+
+// unscaled returns (v - ValueOffset) / Scale rounded to the nearest integer.
+func (f *Field) unscaled(v float64) float64 {
+	if d := 1 / f.Scale; f.Scale < 1 && d == math.Round(d) {
+		return math.Round((v - f.ValueOffset) * d)
+	}
+	return math.Round((v - f.ValueOffset) / f.Scale)
+}
+
+// rawRange returns the lowest and highest raw values of the field, excluding
+// a sentinel at either end. A sentinel of 0 is kept in unsigned fields, as
+// the older formats that use it cannot tell zero readings apart either.
+func (f *Field) rawRange() (lo, hi int64) {
+	width := f.width()
+	if f.Signed {
+		lo, hi = -1<<(width-1), 1<<(width-1)-1
+	} else {
+		lo, hi = 0, 1<<width-1
+	}
+
+	if f.HasSentinel {
+		switch {
+		case f.Sentinel == hi:
+			hi--
+		case f.Sentinel == lo && lo != 0:
+			lo++
+		}
+	}
+	return lo, hi
+}
+
+// put writes the physical value v into the field. Out-of-range values are
+// saturated when opts.Clamp is set and reported as a *RangeError otherwise.
+// Logarithmic fields are always saturated.
+func (f *Field) put(b []byte, v float64, opts EncodeOptions) error {
+	switch f.Kind {
+	case FieldSignMagnitude:
+		return f.putSignMagnitude(b, v, opts)
+
+	case FieldLogarithmic:
+		_, hi := f.rawRange()
+		code := math.Round(math.Log(math.Max(v, 0)+1) / f.Scale)
+		f.putRaw(b, int64(math.Min(code, float64(hi))))
+		return nil
+	}
+
+	lo, hi := f.rawRange()
+	raw := f.unscaled(v)
+	if raw < float64(lo) || raw > float64(hi) {
+		if !opts.Clamp {
+			return &RangeError{Field: f.Label, Value: v, Min: f.scaled(lo), Max: f.scaled(hi)}
+		}
+		raw = math.Max(float64(lo), math.Min(raw, float64(hi)))
+	}
+
+	f.putRaw(b, int64(raw))
+	return nil
+}
+
+// putSignMagnitude writes v as a sign bit and whole units, and a hundredths
+// byte if the field has one. Values are rounded to the hundredth, carrying
+// into the whole units, or truncated to whole units without a hundredths byte.
+func (f *Field) putSignMagnitude(b []byte, v float64, opts EncodeOptions) error {
+	abs := math.Abs(v)
+	maxValue, maxCents := 127.0, 12700.0
+	cents := math.Trunc(abs) * 100
+	if f.Size > 1 {
+		maxValue, maxCents = 127.99, 12799
+		cents = math.Round(abs * 100)
+	}
+	if cents > maxCents {
+		if !opts.Clamp {
+			return &RangeError{Field: f.Label, Value: v, Min: -maxValue, Max: maxValue}
+		}
+		cents = maxCents
+	}
+
+	c := int(cents)
+	b[f.Offset] = byte(c / 100)
+	if v < 0 {
+		b[f.Offset] |= 0x80
+	}
+	if f.Size > 1 {
+		b[f.Offset+1] = byte(c % 100)
+	}
+	return nil
+}
+
+// putSentinel writes the "not available" value of the field, or 0 if the
+// field has none.
+func (f *Field) putSentinel(b []byte) {
+	f.putRaw(b, f.Sentinel)
+}
+
+// putRaw writes a raw integer value into the bits of the field, leaving
+// other bits of the bytes it shares with other fields untouched.
+func (f *Field) putRaw(b []byte, raw int64) {
+	u := uint64(raw) & (1<<f.width() - 1)
+
+	if f.hasLSB() {
+		if u&1 != 0 {
+			b[f.LSBOffset] |= f.LSBMask
+		} else {
+			b[f.LSBOffset] &^= f.LSBMask
+		}
+		u >>= 1
+	}
+
+	bits := f.Bits
+	if bits == 0 {
+		bits = uint(f.Size * 8)
+	}
+	mask := uint64(1)<<bits - 1
+
+	var cur uint64
+	for _, x := range b[f.Offset : f.Offset+f.Size] {
+		cur = cur<<8 | uint64(x)
+	}
+	cur = cur&^(mask<<f.Shift) | (u&mask)<<f.Shift
+
+	for i := f.Size - 1; i >= 0; i-- {
+		b[f.Offset+i] = byte(cur)
+		cur >>= 8
+	}
+}
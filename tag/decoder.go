@@ -7,6 +7,12 @@ import (
 // DataFormat represents the format version of RuuviTag data.
 type DataFormat uint8
 
+// String returns the format identifier as used in the Ruuvi specifications,
+// for example "5" or "C5".
+func (f DataFormat) String() string {
+	return fmt.Sprintf("%X", uint8(f))
+}
+
 const (
 	// Format2 is the URL-based format used on Kickstarter devices (obsolete).
 	Format2 DataFormat = 2
@@ -19,39 +25,69 @@ const (
 
 	// Format5 is RAWv2, the primary format in 2.x and 3.x firmware (in production).
 	Format5 DataFormat = 5
+
+	// Format6 is the Ruuvi Air air quality format (in production).
+	Format6 DataFormat = 6
+
+	// Format8 is the encrypted environmental format, decrypted with a per-tag AES-128 key.
+	Format8 DataFormat = 8
+
+	// FormatC5 is a cut-down RAWv2 without acceleration, used by low-power firmware profiles.
+	FormatC5 DataFormat = 0xC5
+
+	// FormatE1 is the extended air quality format sent over BLE extended advertising (in production).
+	FormatE1 DataFormat = 0xE1
 )
 
 // DetectFormat returns the format version from raw data.
-// Returns an error if the data is too short or contains an unknown format.
+// Returns ErrEmpty if the data is empty and an *UnknownFormatError if no
+// decoder is registered for the format byte.
 func DetectFormat(data []byte) (DataFormat, error) {
 	if len(data) == 0 {
-		return 0, fmt.Errorf("data is empty")
+		return 0, ErrEmpty
 	}
 
 	format := DataFormat(data[0])
-
-	switch format {
-	case Format2, Format3, Format4, Format5:
-		return format, nil
-	default:
-		return 0, fmt.Errorf("unknown format: 0x%02X", data[0])
+	if _, ok := lookupDecoder(format); !ok {
+		return 0, &UnknownFormatError{Format: format}
 	}
+
+	return format, nil
 }
 
 // DecodedData represents decoded RuuviTag data from any supported format.
 // Only one of the format-specific fields will be populated based on the detected format.
 type DecodedData struct {
-	Format  DataFormat
-	Format2 *Format2Data
-	Format3 *Format3Data
-	Format4 *Format4Data
-	Format5 *Format5Data
+	Format   DataFormat
+	Format2  *Format2Data
+	Format3  *Format3Data
+	Format4  *Format4Data
+	Format5  *Format5Data
+	Format6  *Format6Data
+	Format8  *Format8Data
+	FormatC5 *FormatC5Data
+	FormatE1 *FormatE1Data
+	Custom   any // Result of a third-party Decoder, see RegisterFormat
 }
 
-// Decode automatically detects and decodes RuuviTag data from raw bytes.
+// Decode automatically detects and decodes RuuviTag data from raw bytes
+// using the decoder registered for its format byte.
 // Returns a DecodedData structure with the appropriate format field populated.
+//
+// Data Format 8 payloads are decrypted with the key that DefaultKeyStore
+// holds for the tag's MAC address.
 func Decode(data []byte) (*DecodedData, error) {
-	format, err := DetectFormat(data)
+	if len(data) == 0 {
+		return nil, ErrEmpty
+	}
+
+	format := DataFormat(data[0])
+	decoder, ok := lookupDecoder(format)
+	if !ok {
+		return nil, &UnknownFormatError{Format: format}
+	}
+
+	decoded, err := decoder.Decode(data)
 	if err != nil {
 		return nil, err
 	}
@@ -60,38 +96,58 @@ func Decode(data []byte) (*DecodedData, error) {
 		Format: format,
 	}
 
-	switch format {
-	case Format2:
-		decoded, err := DecodeFormat2(data)
-		if err != nil {
-			return nil, err
-		}
-		result.Format2 = decoded
-
-	case Format3:
-		decoded, err := DecodeFormat3(data)
-		if err != nil {
-			return nil, err
-		}
-		result.Format3 = decoded
-
-	case Format4:
-		decoded, err := DecodeFormat4(data)
-		if err != nil {
-			return nil, err
-		}
-		result.Format4 = decoded
-
-	case Format5:
-		decoded, err := DecodeFormat5(data)
-		if err != nil {
-			return nil, err
-		}
-		result.Format5 = decoded
-
+	switch v := decoded.(type) {
+	case *Format2Data:
+		result.Format2 = v
+	case *Format3Data:
+		result.Format3 = v
+	case *Format4Data:
+		result.Format4 = v
+	case *Format5Data:
+		result.Format5 = v
+	case *Format6Data:
+		result.Format6 = v
+	case *Format8Data:
+		result.Format8 = v
+	case *FormatC5Data:
+		result.FormatC5 = v
+	case *FormatE1Data:
+		result.FormatE1 = v
 	default:
-		return nil, fmt.Errorf("unsupported format: %d", format)
+		result.Custom = v
 	}
 
 	return result, nil
 }
+
+// Encode encodes decoded data back into raw bytes with the encoder of its
+// populated format field. It is the inverse of Decode for the formats that
+// support encoding.
+//
+// Data Format 8 is encrypted with the key that DefaultKeyStore holds for its
+// MAC address. Returns ErrEncodingUnsupported for Format E1, custom formats and
+// data without a populated format field.
+func Encode(d *DecodedData) ([]byte, error) {
+	if d == nil {
+		return nil, ErrNilData
+	}
+
+	switch {
+	case d.Format2 != nil:
+		return EncodeFormat2(d.Format2)
+	case d.Format3 != nil:
+		return EncodeFormat3(d.Format3)
+	case d.Format4 != nil:
+		return EncodeFormat4(d.Format4)
+	case d.Format5 != nil:
+		return EncodeFormat5(d.Format5)
+	case d.Format6 != nil:
+		return EncodeFormat6(d.Format6)
+	case d.Format8 != nil:
+		return EncodeFormat8WithKeyStore(d.Format8, DefaultKeyStore)
+	case d.FormatC5 != nil:
+		return EncodeFormatC5(d.FormatC5)
+	default:
+		return nil, fmt.Errorf("%w: format %s", ErrEncodingUnsupported, d.Format)
+	}
+}
@@ -33,6 +33,26 @@ func TestDetectFormat(t *testing.T) {
 			data: []byte{0x05, 0x00, 0x00, 0x00, 0x00, 0x00},
 			want: Format5,
 		},
+		{
+			name: "Format 6",
+			data: []byte{0x06, 0x00, 0x00, 0x00, 0x00, 0x00},
+			want: Format6,
+		},
+		{
+			name: "Format 8",
+			data: []byte{0x08, 0x00, 0x00, 0x00, 0x00, 0x00},
+			want: Format8,
+		},
+		{
+			name: "Format C5",
+			data: []byte{0xC5, 0x00, 0x00, 0x00, 0x00, 0x00},
+			want: FormatC5,
+		},
+		{
+			name: "Format E1",
+			data: []byte{0xE1, 0x00, 0x00, 0x00, 0x00, 0x00},
+			want: FormatE1,
+		},
 		{
 			name:    "Empty data",
 			data:    []byte{},
@@ -77,6 +97,21 @@ func TestDecode(t *testing.T) {
 			hex:  "03291A1ECE1EFC18F94202CA0B53",
 			want: Format3,
 		},
+		{
+			name: "Format 6 valid",
+			hex:  "06170C5668C79E007000C90501D9FFCD004C884F",
+			want: Format6,
+		},
+		{
+			name: "Format C5 valid",
+			hex:  "C512FC5394C37CAC364200CDCBB8334C884F",
+			want: FormatC5,
+		},
+		{
+			name: "Format E1 valid",
+			hex:  "E1170C5668C79E000B0070009100A300C905010013DE6D647A0000CD00FFFFFFFFFFCBB8334C884F",
+			want: FormatE1,
+		},
 		{
 			name:    "Empty data",
 			hex:     "",
@@ -131,6 +166,18 @@ func TestDecode(t *testing.T) {
 				if got.Format5 == nil {
 					t.Error("Format5 data should not be nil")
 				}
+			case Format6:
+				if got.Format6 == nil {
+					t.Error("Format6 data should not be nil")
+				}
+			case FormatC5:
+				if got.FormatC5 == nil {
+					t.Error("FormatC5 data should not be nil")
+				}
+			case FormatE1:
+				if got.FormatE1 == nil {
+					t.Error("FormatE1 data should not be nil")
+				}
 			}
 		})
 	}
@@ -1,8 +1,9 @@
 // Package tag provides decoders and encoders for RuuviTag Bluetooth LE advertisement data formats.
 //
 // This package supports decoding RuuviTag sensor data from broadcast advertisements
-// according to the official Ruuvi Sensor Protocol specifications. It handles formats 2–5,
-// with Format 5 (RAWv2) being the current production standard.
+// according to the official Ruuvi Sensor Protocol specifications. It handles formats 2–6,
+// with Format 5 (RAWv2) being the current production standard for RuuviTags and
+// Format 6 being used by Ruuvi Air air quality monitors.
 //
 // # Basic Usage
 //
@@ -30,23 +31,58 @@
 //	    fmt.Printf("Temp: %.2f°C\n", *decoded.Format3.Temperature)
 //	}
 //
+// To work with any format through a single normalized type, use
+// ParseManufacturerData or DecodedData.Measurement:
+//
+//	m, err := tag.ParseManufacturerData(raw)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	if m.Temperature != nil {
+//	    fmt.Printf("Temp: %.2f°C (format %s)\n", *m.Temperature, m.Format)
+//	}
+//
 // # Data Validation
 //
 // All sensor fields are pointer types. A nil value indicates that the sensor
 // reading is invalid or unavailable. This follows the Ruuvi specification's
 // approach to marking unavailable data with specific sentinel values.
 //
+// For high packet rates, DecodeFormat5Into decodes into a caller-owned
+// Format5Reading without allocating, marking the available fields in its
+// Valid bitmask instead.
+//
 // # Format Support
 //
-// - Format 2: URL-based (obsolete, Kickstarter devices)
+// - Format 2: URL-based (obsolete, Kickstarter devices, see DecodeFormat2URL)
 // - Format 3: RAWv1 (deprecated but widely deployed)
-// - Format 4: URL with ID (obsolete, pre-June 2018)
+// - Format 4: URL with ID (obsolete, pre-June 2018, see DecodeFormat4URL)
 // - Format 5: RAWv2 (current production standard)
+// - Format 6: Ruuvi Air (PM2.5, CO2, VOC, NOx and luminosity)
+// - Format 8: Encrypted environmental data (requires a per-tag key, see KeyStore)
+// - Format C5: Cut-down RAWv2 without acceleration (low-power firmware)
+// - Format E1: Extended Ruuvi Air (full air quality and sound level set, decoding only)
+//
+// Further formats, such as private formats of patched firmware, can be plugged
+// into DetectFormat and Decode with RegisterFormat.
 //
 // Format 5 is recommended for new applications as it provides the most comprehensive
 // sensor data including MAC address, movement counter, and measurement sequence for
 // deduplication.
 //
+// # Units
+//
+// Measurement.Quantities returns the values of a measurement as the typed
+// quantities of package common, such as common.Temperature, which convert to
+// other units and format in metric or imperial units.
+//
+// # Reception Metadata
+//
+// Envelope pairs DecodedData with the reception metadata of the advertisement
+// (timestamp, receiver, Bluetooth address, RSSI and raw payload). NewEnvelope
+// decodes a payload into one, and EnvelopeMAC identifies the sending tag even
+// for formats that only carry part of its MAC address.
+//
 // # Encoding Support (Experimental)
 //
 // Encoding support is currently EXPERIMENTAL and limited to Data Format 5 (RAWv2) only.
@@ -58,8 +94,20 @@
 //
 // See EncodeFormat5 and EncodeFormat5ManufacturerData for details.
 //
+// # Debugging Payloads
+//
+// Explain breaks a payload of any built-in format down into its fields, with
+// the bytes, raw value, scaling and "not available" sentinel of each.
+//
+// Fields returns the layout tables that drive the built-in decoders and
+// encoders, for tools that need to enumerate the fields and units of a format.
+//
 // # References
 //
 // Official specifications: https://github.com/ruuvi/ruuvi-sensor-protocols
 // Format 5 specification: https://github.com/ruuvi/ruuvi-sensor-protocols/blob/master/dataformat_05.md
+// Format 6 specification: https://github.com/ruuvi/ruuvi-sensor-protocols/blob/master/dataformat_06.md
+// Format 8 specification: https://github.com/ruuvi/ruuvi-sensor-protocols/blob/master/dataformat_08.md
+// Format C5 specification: https://github.com/ruuvi/ruuvi-sensor-protocols/blob/master/dataformat_c5.md
+// Format E1 specification: https://github.com/ruuvi/ruuvi-sensor-protocols/blob/master/dataformat_e1.md
 package tag
@@ -0,0 +1,180 @@
+package tag
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// Eddystone-URL frame constants.
+const (
+	// eddystoneUUID is the 16-bit service UUID of Eddystone frames.
+	eddystoneUUID uint16 = 0xFEAA
+
+	// eddystoneURLFrame is the Eddystone frame type of URL frames.
+	eddystoneURLFrame byte = 0x10
+)
+
+// eddystoneSchemes maps Eddystone-URL scheme prefix codes to their expansion.
+var eddystoneSchemes = []string{"http://www.", "https://www.", "http://", "https://"}
+
+// eddystoneExpansions maps Eddystone-URL expansion codes (0x00-0x0D) to their expansion.
+var eddystoneExpansions = []string{
+	".com/", ".org/", ".edu/", ".net/", ".info/", ".biz/", ".gov/",
+	".com", ".org", ".edu", ".net", ".info", ".biz", ".gov",
+}
+
+// ruuviURLPrefix precedes the base64-encoded payload in Format 2 and 4 URLs.
+const ruuviURLPrefix = "ruu.vi/#"
+
+// eddystoneMaxURLLength is the maximum length of the encoded URL in an Eddystone-URL frame.
+const eddystoneMaxURLLength = 17
+
+// ExpandEddystoneURL expands an Eddystone-URL frame into a URL string,
+// replacing the scheme prefix code and expansion codes with their text.
+// The frame starts at the frame type byte (0x10) and holds the calibrated TX
+// power at 0 m in dBm, the URL scheme prefix code and the encoded URL.
+func ExpandEddystoneURL(frame []byte) (url string, txPower int8, err error) {
+	return expandEddystoneURL(frame)
+}
+
+// EncodeEddystoneURL builds an Eddystone-URL frame for url, starting at the
+// frame type byte. The scheme and well-known domain suffixes are replaced by
+// their prefix and expansion codes. Returns an error if the scheme is not
+// supported or the encoded URL exceeds 17 bytes.
+func EncodeEddystoneURL(url string, txPower int8) ([]byte, error) {
+	scheme := -1
+	for i, prefix := range eddystoneSchemes {
+		// Prefer the longest matching prefix, "https://www." over "https://"
+		if strings.HasPrefix(url, prefix) && (scheme < 0 || len(prefix) > len(eddystoneSchemes[scheme])) {
+			scheme = i
+		}
+	}
+	if scheme < 0 {
+		return nil, fmt.Errorf("%w: unsupported scheme in %q", ErrInvalidEddystoneURL, url)
+	}
+
+	frame := []byte{eddystoneURLFrame, byte(txPower), byte(scheme)}
+	rest := url[len(eddystoneSchemes[scheme]):]
+	for len(rest) > 0 {
+		code := -1
+		for i, expansion := range eddystoneExpansions {
+			if strings.HasPrefix(rest, expansion) {
+				code = i
+				break
+			}
+		}
+
+		if code >= 0 {
+			frame = append(frame, byte(code))
+			rest = rest[len(eddystoneExpansions[code]):]
+			continue
+		}
+
+		if c := rest[0]; c <= 0x20 || c >= 0x7F {
+			return nil, fmt.Errorf("%w: invalid character 0x%02X", ErrInvalidEddystoneURL, c)
+		}
+		frame = append(frame, rest[0])
+		rest = rest[1:]
+	}
+
+	if n := len(frame) - 3; n > eddystoneMaxURLLength {
+		return nil, fmt.Errorf("%w: encodes to %d bytes, maximum is %d", ErrInvalidEddystoneURL, n, eddystoneMaxURLLength)
+	}
+
+	return frame, nil
+}
+
+// DecodeURL decodes a Ruuvi URL such as "https://ruu.vi/#BEgAAMQ4T" broadcast
+// by Formats 2 and 4. The scheme is optional.
+// Returns a DecodedData structure with the appropriate format field populated.
+func DecodeURL(url string) (*DecodedData, error) {
+	data, err := decodeRuuviURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return Decode(data)
+}
+
+// DecodeEddystoneURL decodes a raw Eddystone-URL frame, starting at the frame
+// type byte, that carries a Ruuvi URL.
+func DecodeEddystoneURL(frame []byte) (*DecodedData, error) {
+	url, _, err := expandEddystoneURL(frame)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeURL(url)
+}
+
+// encodeRuuviURL builds a Ruuvi URL from a raw Format 2 or Format 4 payload,
+// keeping the first n characters of its URL-safe base64 encoding.
+func encodeRuuviURL(data []byte, n int) string {
+	return "https://" + ruuviURLPrefix + base64.RawURLEncoding.EncodeToString(data)[:n]
+}
+
+// expandEddystoneURL expands an Eddystone-URL frame into a URL string.
+// The frame starts at the frame type byte and holds the calibrated TX power,
+// the URL scheme prefix code and the encoded URL.
+func expandEddystoneURL(frame []byte) (url string, txPower int8, err error) {
+	if len(frame) < 3 {
+		return "", 0, fmt.Errorf("%w: frame requires at least 3 bytes, got %d", ErrInvalidEddystoneURL, len(frame))
+	}
+
+	if frame[0] != eddystoneURLFrame {
+		return "", 0, fmt.Errorf("%w: frame type is 0x%02X", ErrInvalidEddystoneURL, frame[0])
+	}
+
+	if int(frame[2]) >= len(eddystoneSchemes) {
+		return "", 0, fmt.Errorf("%w: unknown scheme code 0x%02X", ErrInvalidEddystoneURL, frame[2])
+	}
+
+	var b strings.Builder
+	b.WriteString(eddystoneSchemes[frame[2]])
+	for _, c := range frame[3:] {
+		switch {
+		case int(c) < len(eddystoneExpansions):
+			b.WriteString(eddystoneExpansions[c])
+		case c > 0x20 && c < 0x7F:
+			b.WriteByte(c)
+		default:
+			return "", 0, fmt.Errorf("%w: invalid character 0x%02X", ErrInvalidEddystoneURL, c)
+		}
+	}
+
+	return b.String(), int8(frame[1]), nil
+}
+
+// decodeRuuviURL extracts the raw Format 2 or Format 4 payload from a Ruuvi URL
+// such as "https://ruu.vi/#BEgAAMQ4T". The scheme is optional.
+//
+// Format 2 URLs carry 8 characters of URL-safe base64 (6 bytes). Format 4 URLs
+// append a ninth character holding the 6 most significant bits of the tag ID.
+func decodeRuuviURL(url string) ([]byte, error) {
+	_, rest, found := strings.Cut(url, "://")
+	if !found {
+		rest = url
+	}
+
+	encoded, ok := strings.CutPrefix(rest, ruuviURLPrefix)
+	if !ok {
+		return nil, fmt.Errorf("%w: not a Ruuvi URL: %q", ErrInvalidEddystoneURL, url)
+	}
+
+	switch len(encoded) {
+	case 8:
+	case 9:
+		// Pad the tag ID character so that it decodes into a full byte
+		encoded += "A"
+	default:
+		return nil, fmt.Errorf("%w: Ruuvi URL payload must be 8 or 9 characters, got %d", ErrInvalidEddystoneURL, len(encoded))
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: Ruuvi URL payload: %w", ErrInvalidEddystoneURL, err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,189 @@
+package tag
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExpandEddystoneURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		frame   []byte
+		want    string
+		wantErr bool
+	}{
+		{name: "ruuvi", frame: append([]byte{0x10, 0xC4, 0x03}, "ruu.vi/#AjwYAMFc"...), want: "https://ruu.vi/#AjwYAMFc"},
+		{name: "expansion", frame: []byte{0x10, 0x00, 0x01, 'r', 'u', 'u', 'v', 'i', 0x00, 'x'}, want: "https://www.ruuvi.com/x"},
+		{name: "too short", frame: []byte{0x10, 0x00}, wantErr: true},
+		{name: "UID frame", frame: []byte{0x00, 0x00, 0x00}, wantErr: true},
+		{name: "bad scheme", frame: []byte{0x10, 0x00, 0x04}, wantErr: true},
+		{name: "bad character", frame: []byte{0x10, 0x00, 0x03, 0x7F}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := expandEddystoneURL(tt.frame)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expandEddystoneURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrInvalidEddystoneURL) {
+				t.Errorf("expandEddystoneURL() error = %v, want ErrInvalidEddystoneURL", err)
+			}
+			if got != tt.want {
+				t.Errorf("expandEddystoneURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeRuuviURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		want    []byte
+		wantErr bool
+	}{
+		{name: "format 2", url: "https://ruu.vi/#AjwYAMFc", want: []byte{0x02, 0x3C, 0x18, 0x00, 0xC1, 0x5C}},
+		{name: "format 4", url: "https://ruu.vi/#BEgAAMQ4T", want: []byte{0x04, 0x48, 0x00, 0x00, 0xC4, 0x38, 0x4C}},
+		{name: "no scheme", url: "ruu.vi/#AjwYAMFc", want: []byte{0x02, 0x3C, 0x18, 0x00, 0xC1, 0x5C}},
+		{name: "other host", url: "https://example.com/#AjwYAMFc", wantErr: true},
+		{name: "bad length", url: "https://ruu.vi/#AjwY", wantErr: true},
+		{name: "bad base64", url: "https://ruu.vi/#Ajw*AMFc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeRuuviURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeRuuviURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrInvalidEddystoneURL) {
+				t.Errorf("decodeRuuviURL() error = %v, want ErrInvalidEddystoneURL", err)
+			}
+			if !bytesEqual(got, tt.want) {
+				t.Errorf("decodeRuuviURL() = %X, want %X", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeEddystoneURL_RoundTrip(t *testing.T) {
+	urls := []string{
+		"https://ruu.vi/#AjwYAMFc",
+		"https://ruu.vi/#BEgAAMQ4T",
+		"http://www.ruuvi.com/x",
+		"https://example.org",
+	}
+
+	for _, url := range urls {
+		frame, err := EncodeEddystoneURL(url, -12)
+		if err != nil {
+			t.Fatalf("EncodeEddystoneURL(%q) error: %v", url, err)
+		}
+
+		got, tx, err := ExpandEddystoneURL(frame)
+		if err != nil {
+			t.Fatalf("ExpandEddystoneURL() error: %v", err)
+		}
+		if got != url {
+			t.Errorf("round trip = %q, want %q", got, url)
+		}
+		if tx != -12 {
+			t.Errorf("TX power = %d, want -12", tx)
+		}
+	}
+}
+
+func TestEncodeEddystoneURL_Compression(t *testing.T) {
+	frame, err := EncodeEddystoneURL("https://www.ruuvi.com/x", 0)
+	if err != nil {
+		t.Fatalf("EncodeEddystoneURL() error: %v", err)
+	}
+
+	want := []byte{0x10, 0x00, 0x01, 'r', 'u', 'u', 'v', 'i', 0x00, 'x'}
+	if !bytesEqual(frame, want) {
+		t.Errorf("EncodeEddystoneURL() = % X, want % X", frame, want)
+	}
+}
+
+func TestEncodeEddystoneURL_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{name: "no scheme", url: "ruu.vi/#AjwYAMFc"},
+		{name: "unsupported scheme", url: "ftp://ruu.vi"},
+		{name: "invalid character", url: "https://ruu vi"},
+		{name: "too long", url: "https://ruu.vi/#BEgAAMQ4TAAAA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := EncodeEddystoneURL(tt.url, 0); !errors.Is(err, ErrInvalidEddystoneURL) {
+				t.Errorf("EncodeEddystoneURL(%q) error = %v, want ErrInvalidEddystoneURL", tt.url, err)
+			}
+		})
+	}
+}
+
+func TestDecodeEddystoneURL(t *testing.T) {
+	frame := append([]byte{0x10, 0xC4, 0x03}, "ruu.vi/#BEgAAMQ4T"...)
+
+	decoded, err := DecodeEddystoneURL(frame)
+	if err != nil {
+		t.Fatalf("DecodeEddystoneURL() error: %v", err)
+	}
+	if decoded.Format != Format4 || decoded.Format4 == nil {
+		t.Fatalf("DecodeEddystoneURL() format = %s, want Format 4 data", decoded.Format)
+	}
+
+	if _, err := DecodeEddystoneURL([]byte{0x10, 0x00, 0x03, 'x'}); !errors.Is(err, ErrInvalidEddystoneURL) {
+		t.Errorf("DecodeEddystoneURL() error = %v for non-Ruuvi URL, want ErrInvalidEddystoneURL", err)
+	}
+}
+
+func TestFormat2URL_RoundTrip(t *testing.T) {
+	const url = "https://ruu.vi/#AjwYAMFc"
+
+	decoded, err := DecodeFormat2URL(url)
+	if err != nil {
+		t.Fatalf("DecodeFormat2URL() error: %v", err)
+	}
+
+	encoded, err := EncodeFormat2URL(decoded)
+	if err != nil {
+		t.Fatalf("EncodeFormat2URL() error: %v", err)
+	}
+	if encoded != url {
+		t.Errorf("EncodeFormat2URL() = %q, want %q", encoded, url)
+	}
+
+	if _, err := DecodeFormat2URL("https://ruu.vi/#BEgAAMQ4T"); !errors.Is(err, ErrInvalidLength) {
+		t.Errorf("DecodeFormat2URL(format 4 URL) error = %v, want ErrInvalidLength", err)
+	}
+
+	if _, err := EncodeFormat2URL(nil); !errors.Is(err, ErrNilData) {
+		t.Errorf("EncodeFormat2URL(nil) error = %v, want ErrNilData", err)
+	}
+}
+
+func TestFormat4URL_RoundTrip(t *testing.T) {
+	const url = "https://ruu.vi/#BEgAAMQ4T"
+
+	decoded, err := DecodeFormat4URL(url)
+	if err != nil {
+		t.Fatalf("DecodeFormat4URL() error: %v", err)
+	}
+
+	encoded, err := EncodeFormat4URL(decoded)
+	if err != nil {
+		t.Fatalf("EncodeFormat4URL() error: %v", err)
+	}
+	if encoded != url {
+		t.Errorf("EncodeFormat4URL() = %q, want %q", encoded, url)
+	}
+
+	if _, err := DecodeFormat4URL("ruu.vi/#AjwYAMFc"); !errors.Is(err, ErrInvalidLength) {
+		t.Errorf("DecodeFormat4URL(format 2 URL) error = %v, want ErrInvalidLength", err)
+	}
+}
@@ -0,0 +1,39 @@
+package tag
+
+import "github.com/marcgeld/ruuvi/common"
+
+// Envelope is decoded data together with when, where and how strongly the
+// advertisement was received. It is the unit passed between the CLI, the
+// exporters and the stream package.
+type Envelope = common.Envelope[*DecodedData]
+
+// NewEnvelope decodes data like Decode and wraps the result in an Envelope
+// holding a copy of data as Raw. The caller fills in the reception metadata.
+func NewEnvelope(data []byte) (*Envelope, error) {
+	decoded, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Envelope{Raw: append(common.HexBytes(nil), data...), Data: decoded}, nil
+}
+
+// EnvelopeMAC returns the MAC address of the tag that sent e: the address
+// carried in the decoded data, or the BLE address for formats that do not
+// carry it in full. Returns false if neither is known.
+func EnvelopeMAC(e *Envelope) (common.MACAddress, bool) {
+	if e == nil {
+		return common.MACAddress{}, false
+	}
+
+	if e.Data != nil {
+		if m := e.Data.Measurement(); m != nil && m.MACAddress != nil {
+			return *m.MACAddress, true
+		}
+	}
+
+	if e.Address != nil {
+		return *e.Address, true
+	}
+	return common.MACAddress{}, false
+}
@@ -0,0 +1,60 @@
+package tag
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/marcgeld/ruuvi/common"
+)
+
+func TestNewEnvelope(t *testing.T) {
+	data := mustDecodeHex(t, jsonTestVectors["format5"])
+	env, err := NewEnvelope(data)
+	if err != nil {
+		t.Fatalf("NewEnvelope failed: %v", err)
+	}
+	if env.Data == nil || env.Data.Format != Format5 || !bytesEqual(env.Raw, data) {
+		t.Fatalf("NewEnvelope = %+v", env)
+	}
+
+	// Raw is a copy
+	data[1] ^= 0xFF
+	if bytesEqual(env.Raw, data) {
+		t.Error("Raw shares memory with the payload")
+	}
+
+	if _, err := NewEnvelope(nil); !errors.Is(err, ErrEmpty) {
+		t.Errorf("NewEnvelope(nil) error = %v, want ErrEmpty", err)
+	}
+}
+
+func TestEnvelopeMAC(t *testing.T) {
+	address := common.MACAddress{0xC0, 0xFF, 0xEE, 0x00, 0x00, 0x01}
+
+	// Format 5 carries the full MAC address, which wins over the BLE address
+	env, err := NewEnvelope(mustDecodeHex(t, jsonTestVectors["format5"]))
+	if err != nil {
+		t.Fatalf("NewEnvelope failed: %v", err)
+	}
+	env.Address = &address
+	if mac, ok := EnvelopeMAC(env); !ok || mac != *env.Data.Measurement().MACAddress {
+		t.Errorf("EnvelopeMAC(format 5) = %s, %v", mac, ok)
+	}
+
+	// Format 6 only carries a suffix
+	env, err = NewEnvelope(mustDecodeHex(t, jsonTestVectors["format6"]))
+	if err != nil {
+		t.Fatalf("NewEnvelope failed: %v", err)
+	}
+	if _, ok := EnvelopeMAC(env); ok {
+		t.Error("EnvelopeMAC(format 6) without address = true, want false")
+	}
+	env.Address = &address
+	if mac, ok := EnvelopeMAC(env); !ok || mac != address {
+		t.Errorf("EnvelopeMAC(format 6) = %s, %v; want %s", mac, ok, address)
+	}
+
+	if _, ok := EnvelopeMAC(nil); ok {
+		t.Error("EnvelopeMAC(nil) = true, want false")
+	}
+}
@@ -0,0 +1,151 @@
+package tag
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/marcgeld/ruuvi/common"
+)
+
+// Sentinel errors returned by the decoders and encoders in this package.
+// Use errors.Is to test for them; the typed errors below match the
+// corresponding sentinel.
+var (
+	// ErrEmpty is returned when the input data is empty.
+	ErrEmpty = errors.New("data is empty")
+
+	// ErrUnknownFormat is returned when the format byte does not match any supported format.
+	ErrUnknownFormat = errors.New("unknown format")
+
+	// ErrInvalidLength is returned when the input length does not match the format.
+	ErrInvalidLength = errors.New("invalid data length")
+
+	// ErrFormatMismatch is returned when a format-specific decoder is given data of another format.
+	ErrFormatMismatch = errors.New("format byte mismatch")
+
+	// ErrNilData is returned when an encoder is given nil data.
+	ErrNilData = errors.New("data cannot be nil")
+
+	// ErrInvalidKey is returned when a Data Format 8 key is not 16 bytes long.
+	ErrInvalidKey = errors.New("invalid format 8 key")
+
+	// ErrKeyNotFound is returned when no Data Format 8 decryption key is known for a tag.
+	ErrKeyNotFound = errors.New("format 8 key not found")
+
+	// ErrCRCMismatch is returned when decrypted Data Format 8 data fails its checksum.
+	ErrCRCMismatch = errors.New("format 8 CRC mismatch")
+
+	// ErrMalformedAdvertisement is returned when advertising data is not a valid sequence of AD structures.
+	ErrMalformedAdvertisement = errors.New("malformed advertisement")
+
+	// ErrNoRuuviData is returned when an advertisement carries no Ruuvi sensor data.
+	ErrNoRuuviData = errors.New("no Ruuvi data in advertisement")
+
+	// ErrInvalidEddystoneURL is returned when an Eddystone-URL frame, or the
+	// Ruuvi URL of Formats 2 and 4 it carries, cannot be encoded or decoded.
+	ErrInvalidEddystoneURL = errors.New("invalid eddystone-URL")
+
+	// ErrEncodingUnsupported is returned when data of a format without an encoder is encoded.
+	ErrEncodingUnsupported = errors.New("encoding not supported")
+
+	// ErrOutOfRange is returned when an encoder is given a value the format cannot represent.
+	ErrOutOfRange = errors.New("value out of range")
+)
+
+// UnknownFormatError is returned when the format byte does not match any
+// supported format. It matches ErrUnknownFormat.
+type UnknownFormatError struct {
+	Format DataFormat // Format byte found in the data
+}
+
+func (e *UnknownFormatError) Error() string {
+	return fmt.Sprintf("unknown format: 0x%02X", uint8(e.Format))
+}
+
+// Is reports whether target is ErrUnknownFormat.
+func (e *UnknownFormatError) Is(target error) bool {
+	return target == ErrUnknownFormat
+}
+
+// LengthError is returned when the input length does not match the length
+// required by a format. It matches ErrInvalidLength.
+type LengthError struct {
+	Format DataFormat // Format being decoded
+	Want   int        // Required length in bytes
+	Got    int        // Actual length in bytes
+}
+
+func (e *LengthError) Error() string {
+	return fmt.Sprintf("format %s requires exactly %d bytes, got %d", e.Format, e.Want, e.Got)
+}
+
+// Is reports whether target is ErrInvalidLength.
+func (e *LengthError) Is(target error) bool {
+	return target == ErrInvalidLength
+}
+
+// FormatMismatchError is returned when a format-specific decoder is given
+// data whose format byte belongs to another format. It matches ErrFormatMismatch.
+type FormatMismatchError struct {
+	Want DataFormat // Format expected by the decoder
+	Got  DataFormat // Format byte found in the data
+}
+
+func (e *FormatMismatchError) Error() string {
+	return fmt.Sprintf("not format %s data: format byte is 0x%02X", e.Want, uint8(e.Got))
+}
+
+// Is reports whether target is ErrFormatMismatch.
+func (e *FormatMismatchError) Is(target error) bool {
+	return target == ErrFormatMismatch
+}
+
+// KeyNotFoundError is returned when no Data Format 8 decryption key is known
+// for the MAC address of a tag. It matches ErrKeyNotFound.
+type KeyNotFoundError struct {
+	MAC common.MACAddress
+}
+
+func (e *KeyNotFoundError) Error() string {
+	return fmt.Sprintf("no format 8 decryption key for tag %s", e.MAC)
+}
+
+// Is reports whether target is ErrKeyNotFound.
+func (e *KeyNotFoundError) Is(target error) bool {
+	return target == ErrKeyNotFound
+}
+
+// CRCError is returned when the CRC8 of decrypted Data Format 8 data does not
+// match the transmitted checksum, usually because the key is wrong.
+// It matches ErrCRCMismatch.
+type CRCError struct {
+	Want uint8 // CRC transmitted in the payload
+	Got  uint8 // CRC computed over the decrypted data
+}
+
+func (e *CRCError) Error() string {
+	return fmt.Sprintf("format 8 CRC mismatch: payload has 0x%02X, decrypted data has 0x%02X", e.Want, e.Got)
+}
+
+// Is reports whether target is ErrCRCMismatch.
+func (e *CRCError) Is(target error) bool {
+	return target == ErrCRCMismatch
+}
+
+// RangeError is returned when an encoder is given a value outside the range
+// the format can represent. It matches ErrOutOfRange.
+type RangeError struct {
+	Field string  // Name of the field, e.g. "temperature"
+	Value float64 // Value given to the encoder
+	Min   float64 // Lowest representable value
+	Max   float64 // Highest representable value
+}
+
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("%s %g out of range [%g, %g]", e.Field, e.Value, e.Min, e.Max)
+}
+
+// Is reports whether target is ErrOutOfRange.
+func (e *RangeError) Is(target error) bool {
+	return target == ErrOutOfRange
+}
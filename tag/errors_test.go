@@ -0,0 +1,138 @@
+package tag
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrors_DetectFormat(t *testing.T) {
+	if _, err := DetectFormat(nil); !errors.Is(err, ErrEmpty) {
+		t.Errorf("DetectFormat(nil) error = %v, want ErrEmpty", err)
+	}
+
+	_, err := DetectFormat([]byte{0xFF, 0x00})
+	if !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("DetectFormat(0xFF) error = %v, want ErrUnknownFormat", err)
+	}
+	var unknown *UnknownFormatError
+	if !errors.As(err, &unknown) || unknown.Format != 0xFF {
+		t.Errorf("DetectFormat(0xFF) error = %v, want *UnknownFormatError for 0xFF", err)
+	}
+	if got, want := err.Error(), "unknown format: 0xFF"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestErrors_Decode(t *testing.T) {
+	if _, err := Decode(nil); !errors.Is(err, ErrEmpty) {
+		t.Errorf("Decode(nil) error = %v, want ErrEmpty", err)
+	}
+
+	if _, err := Decode([]byte{0xFF}); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Decode(0xFF) error = %v, want ErrUnknownFormat", err)
+	}
+
+	_, err := Decode([]byte{0x05, 0x12, 0xFC})
+	var lengthErr *LengthError
+	if !errors.As(err, &lengthErr) {
+		t.Fatalf("Decode(short) error = %v, want *LengthError", err)
+	}
+	if lengthErr.Format != Format5 || lengthErr.Want != 24 || lengthErr.Got != 3 {
+		t.Errorf("LengthError = %+v, want {Format5 24 3}", *lengthErr)
+	}
+	if !errors.Is(err, ErrInvalidLength) {
+		t.Errorf("Decode(short) error = %v, want ErrInvalidLength", err)
+	}
+}
+
+func TestErrors_FormatDecoders(t *testing.T) {
+	decoders := []struct {
+		format DataFormat
+		length int
+		decode func([]byte) error
+	}{
+		{Format2, 6, func(b []byte) error { _, err := DecodeFormat2(b); return err }},
+		{Format3, 14, func(b []byte) error { _, err := DecodeFormat3(b); return err }},
+		{Format4, 7, func(b []byte) error { _, err := DecodeFormat4(b); return err }},
+		{Format5, 24, func(b []byte) error { _, err := DecodeFormat5(b); return err }},
+		{Format6, 20, func(b []byte) error { _, err := DecodeFormat6(b); return err }},
+		{Format8, 24, func(b []byte) error { _, err := DecodeFormat8(b, make([]byte, Format8KeySize)); return err }},
+		{FormatC5, 18, func(b []byte) error { _, err := DecodeFormatC5(b); return err }},
+		{FormatE1, 40, func(b []byte) error { _, err := DecodeFormatE1(b); return err }},
+	}
+
+	for _, d := range decoders {
+		t.Run(d.format.String(), func(t *testing.T) {
+			err := d.decode([]byte{byte(d.format)})
+			var lengthErr *LengthError
+			if !errors.As(err, &lengthErr) || lengthErr.Format != d.format || lengthErr.Want != d.length || lengthErr.Got != 1 {
+				t.Errorf("short input error = %v, want *LengthError{%s %d 1}", err, d.format, d.length)
+			}
+
+			err = d.decode(make([]byte, d.length))
+			var mismatch *FormatMismatchError
+			if !errors.As(err, &mismatch) || mismatch.Want != d.format || mismatch.Got != 0 {
+				t.Errorf("wrong format error = %v, want *FormatMismatchError", err)
+			}
+			if !errors.Is(err, ErrFormatMismatch) {
+				t.Errorf("wrong format error = %v, want ErrFormatMismatch", err)
+			}
+		})
+	}
+}
+
+func TestErrors_Encoders(t *testing.T) {
+	encoders := map[string]func() error{
+		"Format2":  func() error { _, err := EncodeFormat2(nil); return err },
+		"Format3":  func() error { _, err := EncodeFormat3(nil); return err },
+		"Format4":  func() error { _, err := EncodeFormat4(nil); return err },
+		"Format5":  func() error { _, err := EncodeFormat5(nil); return err },
+		"Format6":  func() error { _, err := EncodeFormat6(nil); return err },
+		"Format8":  func() error { _, err := EncodeFormat8(nil, make([]byte, Format8KeySize)); return err },
+		"FormatC5": func() error { _, err := EncodeFormatC5(nil); return err },
+	}
+
+	for name, encode := range encoders {
+		if err := encode(); !errors.Is(err, ErrNilData) {
+			t.Errorf("Encode%s(nil) error = %v, want ErrNilData", name, err)
+		}
+	}
+
+	if _, err := EncodeFormat8(&Format8Data{}, []byte{1, 2, 3}); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("EncodeFormat8(short key) error = %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestErrors_Format8(t *testing.T) {
+	raw := mustDecodeHex(t, format8TestVector)
+
+	if _, err := DecodeFormat8WithKeyStore(raw, nil); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("missing key error = %v, want ErrKeyNotFound", err)
+	}
+
+	if _, err := DecodeFormat8(raw, make([]byte, Format8KeySize)); !errors.Is(err, ErrCRCMismatch) {
+		t.Errorf("wrong key error = %v, want ErrCRCMismatch", err)
+	}
+}
+
+func TestErrors_ParseManufacturerData(t *testing.T) {
+	if _, err := ParseManufacturerData(nil); !errors.Is(err, ErrEmpty) {
+		t.Errorf("ParseManufacturerData(nil) error = %v, want ErrEmpty", err)
+	}
+
+	if _, err := ParseManufacturerData([]byte{0xFF}); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("ParseManufacturerData(0xFF) error = %v, want ErrUnknownFormat", err)
+	}
+
+	if _, err := ParseManufacturerData([]byte{0x05, 0x12}); !errors.Is(err, ErrInvalidLength) {
+		t.Errorf("ParseManufacturerData(short) error = %v, want ErrInvalidLength", err)
+	}
+}
+
+func TestDataFormat_String(t *testing.T) {
+	for f, want := range map[DataFormat]string{Format5: "5", FormatC5: "C5", FormatE1: "E1"} {
+		if got := f.String(); got != want {
+			t.Errorf("DataFormat(0x%02X).String() = %q, want %q", uint8(f), got, want)
+		}
+	}
+}
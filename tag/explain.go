@@ -0,0 +1,212 @@
+package tag
+
+import (
+	"crypto/aes"
+	"fmt"
+	"strings"
+
+	"github.com/marcgeld/ruuvi/common"
+)
+
+// Explanation is a field-by-field breakdown of a payload, see Explain.
+type Explanation struct {
+	Format DataFormat
+	Fields []ExplainedField // In payload order
+}
+
+// ExplainedField describes how one field of a payload maps to its value.
+type ExplainedField struct {
+	Name      string // JSON key of the field, or "format", "reserved", "ciphertext", "crc", ...
+	Offset    int    // Offset of the first byte in the payload, or in the decrypted block
+	Bytes     []byte // Bytes holding the field
+	Decrypted bool   // Offset and Bytes refer to the decrypted Data Format 8 block
+
+	Raw         int64   // Raw integer value, 0 for MAC addresses and multi-byte blocks that are not decoded
+	Scale       float64 // Value = Raw*Scale + ValueOffset for linearly scaled fields, 0 otherwise
+	ValueOffset float64
+
+	Value        any    // float64, string for MAC addresses, nil if not available or not decoded
+	Unit         string // Unit of Value, empty if unitless
+	NotAvailable bool   // Raw is the "not available" sentinel of the field
+	Note         string // Bit positions, non-linear scaling or why bytes are not decoded
+}
+
+// Explain breaks a payload down into its fields, giving for each the bytes it
+// is stored in, its raw integer value, the scaling applied, the resulting
+// value and whether it holds a "not available" sentinel. It is meant for
+// debugging tags that send unexpected values.
+//
+// The encrypted block of Data Format 8 is explained when DefaultKeyStore
+// holds the key of the tag. Returns an *UnknownFormatError for formats
+// without a built-in layout, including formats added with RegisterFormat.
+func Explain(data []byte) (*Explanation, error) {
+	return ExplainWithKeyStore(data, DefaultKeyStore)
+}
+
+// ExplainWithKeyStore explains a payload like Explain, decrypting Data Format
+// 8 with the key that keys holds for the tag. keys may be nil.
+func ExplainWithKeyStore(data []byte, keys KeyStore) (*Explanation, error) {
+	if len(data) == 0 {
+		return nil, ErrEmpty
+	}
+
+	format := DataFormat(data[0])
+	layout, ok := layouts[format]
+	if !ok {
+		return nil, &UnknownFormatError{Format: format}
+	}
+
+	if len(data) != layout.size {
+		return nil, &LengthError{Format: format, Want: layout.size, Got: len(data)}
+	}
+
+	result := &Explanation{Format: format}
+	for i := range layout.fields {
+		result.Fields = append(result.Fields, layout.fields[i].explain(data))
+	}
+
+	if format == Format8 {
+		explainFormat8(result, data, keys)
+	}
+
+	return result, nil
+}
+
+// explainFormat8 annotates the encrypted block and CRC of a Data Format 8
+// payload and appends the fields of the decrypted block if the key is known.
+func explainFormat8(e *Explanation, data []byte, keys KeyStore) {
+	var mac common.MACAddress
+	copy(mac[:], data[18:24])
+
+	ciphertext, crc := &e.Fields[1], &e.Fields[2]
+	ciphertext.Note = "AES-128 encrypted block"
+	crc.Raw = int64(data[17])
+	crc.Note = "CRC-8 of the decrypted block"
+
+	var key []byte
+	if keys != nil {
+		key, _ = keys.Key(mac)
+	}
+	if len(key) != Format8KeySize {
+		ciphertext.Note += fmt.Sprintf(", no key for %s", mac)
+		return
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		ciphertext.Note += fmt.Sprintf(", %v", err)
+		return
+	}
+	plain := make([]byte, aes.BlockSize)
+	block.Decrypt(plain, data[1:17])
+
+	if got := crc8(plain); got != data[17] {
+		crc.Note += fmt.Sprintf(", mismatch: computed 0x%02X, wrong key?", got)
+		return
+	}
+	crc.Note += ", ok"
+
+	for i := range format8PlainLayout.fields {
+		e.Fields = append(e.Fields, format8PlainLayout.fields[i].explain(plain))
+	}
+}
+
+// explain applies the field layout to data.
+func (f *Field) explain(data []byte) ExplainedField {
+	result := ExplainedField{
+		Name:      f.Name,
+		Offset:    f.Offset,
+		Bytes:     append([]byte(nil), data[f.Offset:f.Offset+f.Size]...),
+		Decrypted: f.Encrypted,
+		Unit:      f.Unit,
+	}
+
+	if f.Kind == FieldRaw {
+		if f.Size == 1 {
+			result.Raw = f.rawValue(data)
+		}
+		if f.Name == "format" {
+			result.Value = float64(result.Raw)
+		}
+		return result
+	}
+
+	if f.Kind != FieldMAC {
+		result.Raw = f.rawValue(data)
+	}
+	result.NotAvailable = f.notAvailable(data, result.Raw)
+	result.Note = f.note()
+	if f.Kind == FieldLinear {
+		result.Scale, result.ValueOffset = f.Scale, f.ValueOffset
+	}
+	if !result.NotAvailable {
+		result.Value = f.explainValue(data, result.Raw)
+	}
+
+	return result
+}
+
+// explainValue returns the physical value of the field, with MAC addresses
+// formatted as colon-separated hex.
+func (f *Field) explainValue(data []byte, raw int64) any {
+	if f.Kind == FieldMAC {
+		parts := make([]string, f.Size)
+		for i, b := range data[f.Offset : f.Offset+f.Size] {
+			parts[i] = fmt.Sprintf("%02X", b)
+		}
+		return strings.Join(parts, ":")
+	}
+	return f.float(data, raw)
+}
+
+// note describes the bit positions and scaling of the field where the
+// Scale and ValueOffset of ExplainedField do not tell the whole story.
+func (f *Field) note() string {
+	var notes []string
+
+	if f.Bits > 0 {
+		notes = append(notes, fmt.Sprintf("bits %d-%d", f.Shift+f.Bits-1, f.Shift))
+	}
+
+	switch f.Kind {
+	case FieldLinear:
+		if f.LSBMask != 0 {
+			notes = append(notes, fmt.Sprintf("LSB in byte %d bit %d", f.LSBOffset, bitIndex(f.LSBMask)))
+		}
+	case FieldSignMagnitude:
+		if f.Size > 1 {
+			notes = append(notes, "sign bit and whole units, then hundredths")
+		} else {
+			notes = append(notes, "sign bit and whole units")
+		}
+	case FieldLogarithmic:
+		notes = append(notes, fmt.Sprintf("logarithmic: exp(raw × %.6g) - 1", f.Scale))
+	case FieldFlags:
+		notes = append(notes, fmt.Sprintf("without value LSBs (mask 0x%02X)", f.LSBMask))
+	}
+
+	if f.HasSentinel {
+		notes = append(notes, fmt.Sprintf("n/a = %s", f.sentinelString()))
+	} else if f.Kind == FieldMAC {
+		notes = append(notes, "n/a = all 0xFF")
+	}
+
+	return strings.Join(notes, ", ")
+}
+
+// sentinelString formats the sentinel as it appears in the specifications,
+// e.g. "0x8000" for a signed 16-bit field.
+func (f *Field) sentinelString() string {
+	width := f.width()
+	return fmt.Sprintf("0x%0*X", int(width+3)/4, uint64(f.Sentinel)&(1<<width-1))
+}
+
+// bitIndex returns the index of the lowest set bit of mask.
+func bitIndex(mask uint8) int {
+	for i := range 8 {
+		if mask&(1<<i) != 0 {
+			return i
+		}
+	}
+	return -1
+}
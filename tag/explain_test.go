@@ -0,0 +1,208 @@
+package tag
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// explainedValues returns the explained values of decoded fields by name.
+func explainedValues(e *Explanation) map[string]ExplainedField {
+	fields := make(map[string]ExplainedField)
+	for _, f := range e.Fields {
+		fields[f.Name] = f
+	}
+	return fields
+}
+
+func TestExplain_MatchesDecode(t *testing.T) {
+	useFormat8TestKey(t)
+
+	for name, vector := range jsonTestVectors {
+		t.Run(name, func(t *testing.T) {
+			raw := mustDecodeHex(t, vector)
+			decoded, err := Decode(raw)
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			b, err := json.Marshal(decoded.payload())
+			if err != nil {
+				t.Fatalf("json.Marshal failed: %v", err)
+			}
+			var want map[string]any
+			if err := json.Unmarshal(b, &want); err != nil {
+				t.Fatalf("json.Unmarshal failed: %v", err)
+			}
+
+			e, err := Explain(raw)
+			if err != nil {
+				t.Fatalf("Explain failed: %v", err)
+			}
+			if e.Format != decoded.Format {
+				t.Errorf("Format = %s, want %s", e.Format, decoded.Format)
+			}
+
+			got := explainedValues(e)
+			for key, wantValue := range want {
+				f, ok := got[key]
+				if !ok {
+					t.Errorf("field %s not explained", key)
+					continue
+				}
+				switch w := wantValue.(type) {
+				case nil:
+					if !f.NotAvailable || f.Value != nil {
+						t.Errorf("%s = %v, want not available", key, f.Value)
+					}
+				case float64:
+					v, ok := f.Value.(float64)
+					if !ok || !floatEquals(v, w, 1e-9) {
+						t.Errorf("%s = %v, want %v", key, f.Value, w)
+					}
+				case string:
+					if f.Value != w {
+						t.Errorf("%s = %v, want %v", key, f.Value, w)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestExplain_CoversPayload(t *testing.T) {
+	for format, layout := range layouts {
+		covered := make([]bool, layout.size)
+		for _, f := range layout.fields {
+			for i := f.Offset; i < f.Offset+f.Size; i++ {
+				covered[i] = true
+			}
+		}
+		for i, ok := range covered {
+			if !ok {
+				t.Errorf("format %s: byte %d not covered by any field", format, i)
+			}
+		}
+	}
+}
+
+func TestExplain_Format5(t *testing.T) {
+	e, err := Explain(mustDecodeHex(t, jsonTestVectors["format5"]))
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	fields := explainedValues(e)
+
+	temp := fields["temperature_c"]
+	if temp.Offset != 1 || !bytesEqual(temp.Bytes, []byte{0x12, 0xFC}) || temp.Raw != 4860 ||
+		temp.Scale != 0.005 || temp.ValueOffset != 0 || temp.Unit != "°C" {
+		t.Errorf("temperature_c = %+v", temp)
+	}
+	if temp.Note != "n/a = 0x8000" {
+		t.Errorf("temperature_c note = %q", temp.Note)
+	}
+
+	batt := fields["battery_voltage_mv"]
+	if batt.Raw != 1377 || batt.ValueOffset != 1600 || batt.Note != "bits 15-5, n/a = 0x7FF" {
+		t.Errorf("battery_voltage_mv = %+v", batt)
+	}
+
+	tx := fields["tx_power_dbm"]
+	if tx.Raw != 22 || tx.Scale != 2 || tx.ValueOffset != -40 || tx.Note != "bits 4-0, n/a = 0x1F" {
+		t.Errorf("tx_power_dbm = %+v", tx)
+	}
+}
+
+func TestExplain_NotAvailable(t *testing.T) {
+	e, err := Explain(mustDecodeHex(t, "058000FFFFFFFF800080008000FFFFFFFFFFFFFFFFFFFFFF"))
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+
+	for _, f := range e.Fields {
+		if f.Name == "format" {
+			continue
+		}
+		if !f.NotAvailable || f.Value != nil {
+			t.Errorf("%s: NotAvailable = %v, Value = %v, want not available", f.Name, f.NotAvailable, f.Value)
+		}
+	}
+
+	fields := explainedValues(e)
+	if f := fields["acceleration_x_g"]; f.Raw != -32768 {
+		t.Errorf("acceleration_x_g raw = %d, want -32768", f.Raw)
+	}
+}
+
+func TestExplain_NineBitFields(t *testing.T) {
+	e, err := Explain(mustDecodeHex(t, jsonTestVectors["format_e1"]))
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	fields := explainedValues(e)
+
+	voc := fields["voc_index"]
+	if !strings.Contains(voc.Note, "LSB in byte 28 bit 6") {
+		t.Errorf("voc_index note = %q", voc.Note)
+	}
+
+	flags := fields["flags"]
+	if flags.Raw != int64(mustDecodeHex(t, jsonTestVectors["format_e1"])[28]) {
+		t.Errorf("flags raw = %d", flags.Raw)
+	}
+}
+
+func TestExplain_Format8(t *testing.T) {
+	raw := mustDecodeHex(t, format8TestVector)
+
+	// Without a key only the cleartext fields are explained
+	e, err := ExplainWithKeyStore(raw, nil)
+	if err != nil {
+		t.Fatalf("ExplainWithKeyStore failed: %v", err)
+	}
+	if len(e.Fields) != 4 {
+		t.Fatalf("got %d fields, want 4", len(e.Fields))
+	}
+	if !strings.Contains(e.Fields[1].Note, "no key for CB:B8:33:4C:88:4F") {
+		t.Errorf("ciphertext note = %q", e.Fields[1].Note)
+	}
+
+	// With the key the decrypted block follows
+	useFormat8TestKey(t)
+	e, err = Explain(raw)
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if !strings.HasSuffix(e.Fields[2].Note, ", ok") {
+		t.Errorf("crc note = %q", e.Fields[2].Note)
+	}
+	temp := explainedValues(e)["temperature_c"]
+	if !temp.Decrypted || temp.Offset != 0 {
+		t.Errorf("temperature_c = %+v, want decrypted at offset 0", temp)
+	}
+
+	// A wrong key fails the CRC
+	keys := NewMemoryKeyStore()
+	if err := keys.Set(format8TestMAC, make([]byte, Format8KeySize)); err != nil {
+		t.Fatal(err)
+	}
+	e, err = ExplainWithKeyStore(raw, keys)
+	if err != nil {
+		t.Fatalf("ExplainWithKeyStore failed: %v", err)
+	}
+	if !strings.Contains(e.Fields[2].Note, "mismatch") || len(e.Fields) != 4 {
+		t.Errorf("expected CRC mismatch, got %+v", e.Fields)
+	}
+}
+
+func TestExplain_Errors(t *testing.T) {
+	if _, err := Explain(nil); !errors.Is(err, ErrEmpty) {
+		t.Errorf("Explain(nil) error = %v, want ErrEmpty", err)
+	}
+	if _, err := Explain([]byte{0x42, 0x00}); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Explain(0x42) error = %v, want ErrUnknownFormat", err)
+	}
+	if _, err := Explain([]byte{0x05, 0x00}); !errors.Is(err, ErrInvalidLength) {
+		t.Errorf("Explain(short) error = %v, want ErrInvalidLength", err)
+	}
+}
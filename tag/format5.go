@@ -1,146 +1,178 @@
 // Package tag provides RuuviTag Bluetooth LE advertisement data format decoders.
 package tag
 
-import (
-	"encoding/binary"
-	"errors"
-	"fmt"
-	"math"
-
-	"github.com/marcgeld/ruuvi/common"
-)
+import "github.com/marcgeld/ruuvi/common"
 
 // Format5Data represents decoded RuuviTag Data Format 5 (RAWv2) sensor data.
 // This is the primary format in 2.x and 3.x firmware, in production since January 2019.
 type Format5Data struct {
-	Temperature         *float64           // Temperature in degrees Celsius
-	Humidity            *float64           // Relative humidity in percent
-	Pressure            *int               // Atmospheric pressure in Pascals
-	AccelerationX       *float64           // Acceleration X-axis in G
-	AccelerationY       *float64           // Acceleration Y-axis in G
-	AccelerationZ       *float64           // Acceleration Z-axis in G
-	BatteryVoltage      *int               // Battery voltage in millivolts
-	TxPower             *int               // TX power in dBm
-	MovementCounter     *uint8             // Movement counter (0-254)
-	MeasurementSequence *uint16            // Measurement sequence number (0-65534)
-	MACAddress          *common.MACAddress // 48-bit MAC address
+	Temperature         *float64           `json:"temperature_c"`        // Temperature in degrees Celsius
+	Humidity            *float64           `json:"humidity_percent"`     // Relative humidity in percent
+	Pressure            *int               `json:"pressure_pa"`          // Atmospheric pressure in Pascals
+	AccelerationX       *float64           `json:"acceleration_x_g"`     // Acceleration X-axis in G
+	AccelerationY       *float64           `json:"acceleration_y_g"`     // Acceleration Y-axis in G
+	AccelerationZ       *float64           `json:"acceleration_z_g"`     // Acceleration Z-axis in G
+	BatteryVoltage      *int               `json:"battery_voltage_mv"`   // Battery voltage in millivolts
+	TxPower             *int               `json:"tx_power_dbm"`         // TX power in dBm
+	MovementCounter     *uint8             `json:"movement_counter"`     // Movement counter (0-254)
+	MeasurementSequence *uint16            `json:"measurement_sequence"` // Measurement sequence number (0-65534)
+	MACAddress          *common.MACAddress `json:"mac_address"`          // 48-bit MAC address
 }
 
-// DecodeFormat5 decodes RuuviTag Data Format 5 (RAWv2) from raw bytes.
-// The input must be exactly 24 bytes: 1 byte format ID + 23 bytes data.
-// Returns an error if the data is invalid or not Format 5.
-func DecodeFormat5(data []byte) (*Format5Data, error) {
-	if len(data) != 24 {
-		return nil, fmt.Errorf("format 5 requires exactly 24 bytes, got %d", len(data))
-	}
-
-	if data[0] != 0x05 {
-		return nil, fmt.Errorf("not format 5 data: format byte is 0x%02X", data[0])
-	}
-
-	result := &Format5Data{}
+// Format5Reading holds decoded Data Format 5 values without pointers, so that
+// DecodeFormat5Into can fill it without allocating. Valid tells which fields
+// were available; fields that were not are zero.
+type Format5Reading struct {
+	Temperature         float64           // Temperature in degrees Celsius
+	Humidity            float64           // Relative humidity in percent
+	Pressure            int               // Atmospheric pressure in Pascals
+	AccelerationX       float64           // Acceleration X-axis in G
+	AccelerationY       float64           // Acceleration Y-axis in G
+	AccelerationZ       float64           // Acceleration Z-axis in G
+	BatteryVoltage      int               // Battery voltage in millivolts
+	TxPower             int               // TX power in dBm
+	MovementCounter     uint8             // Movement counter (0-254)
+	MeasurementSequence uint16            // Measurement sequence number (0-65534)
+	MACAddress          common.MACAddress // 48-bit MAC address
+	Valid               Format5Fields     // Fields that were available
+}
 
-	// Temperature: bytes 1-2, signed 16-bit, in 0.005°C increments
-	tempRaw := int16(binary.BigEndian.Uint16(data[1:3]))
-	if tempRaw == -32768 { // 0x8000 = invalid
-		result.Temperature = nil
-	} else {
-		temp := float64(tempRaw) * 0.005
-		result.Temperature = &temp
-	}
+// Format5Fields is a set of Format5Reading fields.
+type Format5Fields uint16
+
+// Format5Reading fields, for use with Format5Reading.Valid.
+const (
+	Format5Temperature Format5Fields = 1 << iota
+	Format5Humidity
+	Format5Pressure
+	Format5AccelerationX
+	Format5AccelerationY
+	Format5AccelerationZ
+	Format5BatteryVoltage
+	Format5TxPower
+	Format5MovementCounter
+	Format5MeasurementSequence
+	Format5MACAddress
+
+	// Format5AllFields is the set of all fields.
+	Format5AllFields Format5Fields = 1<<iota - 1
+)
 
-	// Humidity: bytes 3-4, unsigned 16-bit, in 0.0025% increments
-	humRaw := binary.BigEndian.Uint16(data[3:5])
-	if humRaw == 0xFFFF { // invalid
-		result.Humidity = nil
-	} else {
-		hum := float64(humRaw) * 0.0025
-		result.Humidity = &hum
-	}
+// Has reports whether all fields in f were available.
+func (r *Format5Reading) Has(f Format5Fields) bool {
+	return r.Valid&f == f
+}
 
-	// Pressure: bytes 5-6, unsigned 16-bit, offset by -50000 Pa
-	pressRaw := binary.BigEndian.Uint16(data[5:7])
-	if pressRaw == 0xFFFF { // invalid
-		result.Pressure = nil
-	} else {
-		press := int(pressRaw) + 50000
-		result.Pressure = &press
+func format5Valid(r *Format5Reading) *Format5Fields { return &r.Valid }
+
+// format5Codec lays out Data Format 5: big-endian values after the format
+// byte, with the battery voltage and TX power sharing a 16-bit power info field.
+var format5Codec = formatCodec[Format5Reading]{format: Format5, size: 24, bindings: []binding[Format5Reading]{
+	{Field: formatByte, fill: byte(Format5)},
+	valueField(temperature005(1), Format5Temperature, format5Valid, func(r *Format5Reading) *float64 { return &r.Temperature }),
+	valueField(humidity0025(3), Format5Humidity, format5Valid, func(r *Format5Reading) *float64 { return &r.Humidity }),
+	valueField(pressure50000(5), Format5Pressure, format5Valid, func(r *Format5Reading) *int { return &r.Pressure }),
+	valueField(rawv2Acceleration("acceleration_x_g", "acceleration X", 7), Format5AccelerationX, format5Valid, func(r *Format5Reading) *float64 { return &r.AccelerationX }),
+	valueField(rawv2Acceleration("acceleration_y_g", "acceleration Y", 9), Format5AccelerationY, format5Valid, func(r *Format5Reading) *float64 { return &r.AccelerationY }),
+	valueField(rawv2Acceleration("acceleration_z_g", "acceleration Z", 11), Format5AccelerationZ, format5Valid, func(r *Format5Reading) *float64 { return &r.AccelerationZ }),
+	valueField(rawv2BatteryVoltage(13), Format5BatteryVoltage, format5Valid, func(r *Format5Reading) *int { return &r.BatteryVoltage }),
+	valueField(rawv2TxPower(13), Format5TxPower, format5Valid, func(r *Format5Reading) *int { return &r.TxPower }),
+	valueField(rawv2MovementCounter(15), Format5MovementCounter, format5Valid, func(r *Format5Reading) *uint8 { return &r.MovementCounter }),
+	valueField(rawv2Sequence(16), Format5MeasurementSequence, format5Valid, func(r *Format5Reading) *uint16 { return &r.MeasurementSequence }),
+	valueMACField(macAddress("mac_address", "MAC address", 18, 6), Format5MACAddress, format5Valid, func(r *Format5Reading) *common.MACAddress { return &r.MACAddress }),
+}}
+
+// DecodeFormat5Into decodes RuuviTag Data Format 5 (RAWv2) from raw bytes into
+// r without allocating, for receivers that handle many packets per second.
+// The input must be exactly 24 bytes. On error r is left unchanged.
+func DecodeFormat5Into(data []byte, r *Format5Reading) error {
+	if err := format5Codec.validate(data); err != nil {
+		return err
 	}
 
-	// Acceleration X: bytes 7-8, signed 16-bit in mG
-	accXRaw := int16(binary.BigEndian.Uint16(data[7:9]))
-	if accXRaw == -32768 { // 0x8000 = invalid
-		result.AccelerationX = nil
-	} else {
-		accX := float64(accXRaw) / 1000.0 // Convert mG to G
-		result.AccelerationX = &accX
-	}
+	format5Codec.decode(data, r)
+	return nil
+}
 
-	// Acceleration Y: bytes 9-10, signed 16-bit in mG
-	accYRaw := int16(binary.BigEndian.Uint16(data[9:11]))
-	if accYRaw == -32768 { // 0x8000 = invalid
-		result.AccelerationY = nil
-	} else {
-		accY := float64(accYRaw) / 1000.0 // Convert mG to G
-		result.AccelerationY = &accY
+// DecodeFormat5 decodes RuuviTag Data Format 5 (RAWv2) from raw bytes.
+// The input must be exactly 24 bytes: 1 byte format ID + 23 bytes data.
+// Returns an error if the data is invalid or not Format 5.
+//
+// The result and its values are allocated together; see DecodeFormat5Into
+// for an allocation-free alternative.
+func DecodeFormat5(data []byte) (*Format5Data, error) {
+	if err := format5Codec.validate(data); err != nil {
+		return nil, err
 	}
 
-	// Acceleration Z: bytes 11-12, signed 16-bit in mG
-	accZRaw := int16(binary.BigEndian.Uint16(data[11:13]))
-	if accZRaw == -32768 { // 0x8000 = invalid
-		result.AccelerationZ = nil
-	} else {
-		accZ := float64(accZRaw) / 1000.0 // Convert mG to G
-		result.AccelerationZ = &accZ
-	}
+	b := &format5Backing{}
+	format5Codec.decode(data, &b.reading)
+	return b.link(), nil
+}
 
-	// Power info: bytes 13-14, 11 bits voltage + 5 bits TX power
-	powerInfo := binary.BigEndian.Uint16(data[13:15])
+// Data returns the reading as Format5Data, with nil for fields that were not
+// available. The values pointed to share a single allocation with the
+// returned Format5Data.
+func (r *Format5Reading) Data() *Format5Data {
+	b := &format5Backing{reading: *r}
+	return b.link()
+}
 
-	// Battery voltage: first 11 bits, offset by 1600 mV
-	battRaw := powerInfo >> 5
-	if battRaw == 0x7FF { // 2047 = invalid
-		result.BatteryVoltage = nil
-	} else {
-		batt := int(battRaw) + 1600
-		result.BatteryVoltage = &batt
-	}
+// format5Backing holds a Format5Data and the reading its pointers refer to,
+// so that both take a single allocation.
+type format5Backing struct {
+	data    Format5Data
+	reading Format5Reading
+}
 
-	// TX power: last 5 bits, offset by -40 dBm, in 2 dBm steps
-	txRaw := powerInfo & 0x1F
-	if txRaw == 0x1F { // 31 = invalid
-		result.TxPower = nil
-	} else {
-		tx := int(txRaw)*2 - 40
-		result.TxPower = &tx
-	}
+// link points the fields of b.data to the available values of b.reading.
+func (b *format5Backing) link() *Format5Data {
+	d, r := &b.data, &b.reading
+	d.Temperature = validPtr(&r.Temperature, r.Has(Format5Temperature))
+	d.Humidity = validPtr(&r.Humidity, r.Has(Format5Humidity))
+	d.Pressure = validPtr(&r.Pressure, r.Has(Format5Pressure))
+	d.AccelerationX = validPtr(&r.AccelerationX, r.Has(Format5AccelerationX))
+	d.AccelerationY = validPtr(&r.AccelerationY, r.Has(Format5AccelerationY))
+	d.AccelerationZ = validPtr(&r.AccelerationZ, r.Has(Format5AccelerationZ))
+	d.BatteryVoltage = validPtr(&r.BatteryVoltage, r.Has(Format5BatteryVoltage))
+	d.TxPower = validPtr(&r.TxPower, r.Has(Format5TxPower))
+	d.MovementCounter = validPtr(&r.MovementCounter, r.Has(Format5MovementCounter))
+	d.MeasurementSequence = validPtr(&r.MeasurementSequence, r.Has(Format5MeasurementSequence))
+	d.MACAddress = validPtr(&r.MACAddress, r.Has(Format5MACAddress))
+	return d
+}
 
-	// Movement counter: byte 15
-	movementRaw := data[15]
-	if movementRaw == 0xFF { // 255 = invalid
-		result.MovementCounter = nil
-	} else {
-		result.MovementCounter = &movementRaw
-	}
+// format5Reading returns d as a Format5Reading.
+func format5Reading(d *Format5Data) *Format5Reading {
+	r := &Format5Reading{}
+	setValid(&r.Temperature, &r.Valid, Format5Temperature, d.Temperature)
+	setValid(&r.Humidity, &r.Valid, Format5Humidity, d.Humidity)
+	setValid(&r.Pressure, &r.Valid, Format5Pressure, d.Pressure)
+	setValid(&r.AccelerationX, &r.Valid, Format5AccelerationX, d.AccelerationX)
+	setValid(&r.AccelerationY, &r.Valid, Format5AccelerationY, d.AccelerationY)
+	setValid(&r.AccelerationZ, &r.Valid, Format5AccelerationZ, d.AccelerationZ)
+	setValid(&r.BatteryVoltage, &r.Valid, Format5BatteryVoltage, d.BatteryVoltage)
+	setValid(&r.TxPower, &r.Valid, Format5TxPower, d.TxPower)
+	setValid(&r.MovementCounter, &r.Valid, Format5MovementCounter, d.MovementCounter)
+	setValid(&r.MeasurementSequence, &r.Valid, Format5MeasurementSequence, d.MeasurementSequence)
+	setValid(&r.MACAddress, &r.Valid, Format5MACAddress, d.MACAddress)
+	return r
+}
 
-	// Measurement sequence: bytes 16-17, unsigned 16-bit
-	seqRaw := binary.BigEndian.Uint16(data[16:18])
-	if seqRaw == 0xFFFF { // 65535 = invalid
-		result.MeasurementSequence = nil
-	} else {
-		result.MeasurementSequence = &seqRaw
+// validPtr returns p, or nil if the value it points to is not valid.
+func validPtr[V any](p *V, valid bool) *V {
+	if !valid {
+		return nil
 	}
+	return p
+}
 
-	// MAC address: bytes 18-23
-	var mac common.MACAddress
-	copy(mac[:], data[18:24])
-	if mac.IsInvalid() {
-		result.MACAddress = nil
-	} else {
-		result.MACAddress = &mac
+// setValid copies *p to *v and adds bit to *set, unless p is nil.
+func setValid[V any, M ~uint16](v *V, set *M, bit M, p *V) {
+	if p != nil {
+		*v = *p
+		*set |= bit
 	}
-
-	return result, nil
 }
 
 // EncodeFormat5 encodes Format5Data into raw bytes suitable for BLE advertisement payload.
@@ -170,107 +202,32 @@ func DecodeFormat5(data []byte) (*Format5Data, error) {
 //   - Battery Voltage: 1 mV resolution
 //   - TX Power: 2 dBm resolution
 //
-// Note: Due to quantization, decoding the encoded data may not produce exactly the same
-// values as the input (within the resolution limits of each field).
+// Values are rounded to the nearest resolution step. Note: Due to quantization, decoding
+// the encoded data may not produce exactly the same values as the input (within the
+// resolution limits of each field).
+//
+// Values the format cannot represent are rejected with a *RangeError naming the field
+// and its bounds, e.g. temperatures outside ±163.835°C or pressures outside
+// 50000-115534 Pa. Use EncodeFormat5WithOptions to saturate them instead.
 func EncodeFormat5(data *Format5Data) ([]byte, error) {
-	if data == nil {
-		return nil, errors.New("data cannot be nil")
-	}
-
-	result := make([]byte, 24)
-	result[0] = 0x05 // Format ID
-
-	// Temperature
-	if data.Temperature == nil || math.IsNaN(*data.Temperature) {
-		binary.BigEndian.PutUint16(result[1:3], 0x8000)
-	} else {
-		temp := int16(*data.Temperature / 0.005)
-		binary.BigEndian.PutUint16(result[1:3], uint16(temp))
-	}
-
-	// Humidity
-	if data.Humidity == nil || math.IsNaN(*data.Humidity) {
-		binary.BigEndian.PutUint16(result[3:5], 0xFFFF)
-	} else {
-		hum := uint16(*data.Humidity / 0.0025)
-		binary.BigEndian.PutUint16(result[3:5], hum)
-	}
-
-	// Pressure
-	if data.Pressure == nil {
-		binary.BigEndian.PutUint16(result[5:7], 0xFFFF)
-	} else {
-		press := uint16(*data.Pressure - 50000)
-		binary.BigEndian.PutUint16(result[5:7], press)
-	}
-
-	// Acceleration X
-	if data.AccelerationX == nil || math.IsNaN(*data.AccelerationX) {
-		binary.BigEndian.PutUint16(result[7:9], 0x8000)
-	} else {
-		accX := int16(*data.AccelerationX * 1000)
-		binary.BigEndian.PutUint16(result[7:9], uint16(accX))
-	}
-
-	// Acceleration Y
-	if data.AccelerationY == nil || math.IsNaN(*data.AccelerationY) {
-		binary.BigEndian.PutUint16(result[9:11], 0x8000)
-	} else {
-		accY := int16(*data.AccelerationY * 1000)
-		binary.BigEndian.PutUint16(result[9:11], uint16(accY))
-	}
-
-	// Acceleration Z
-	if data.AccelerationZ == nil || math.IsNaN(*data.AccelerationZ) {
-		binary.BigEndian.PutUint16(result[11:13], 0x8000)
-	} else {
-		accZ := int16(*data.AccelerationZ * 1000)
-		binary.BigEndian.PutUint16(result[11:13], uint16(accZ))
-	}
-
-	// Power info: 11 bits voltage + 5 bits TX power
-	var powerInfo uint16
-
-	if data.BatteryVoltage == nil {
-		powerInfo |= 0x7FF << 5 // 2047 shifted left 5 bits
-	} else {
-		batt := uint16(*data.BatteryVoltage - 1600)
-		powerInfo |= (batt & 0x7FF) << 5
-	}
-
-	if data.TxPower == nil {
-		powerInfo |= 0x1F // 31
-	} else {
-		tx := uint16((*data.TxPower + 40) / 2)
-		powerInfo |= tx & 0x1F
-	}
-
-	binary.BigEndian.PutUint16(result[13:15], powerInfo)
-
-	// Movement counter
-	if data.MovementCounter == nil {
-		result[15] = 0xFF
-	} else {
-		result[15] = *data.MovementCounter
-	}
+	return EncodeFormat5WithOptions(data, EncodeOptions{})
+}
 
-	// Measurement sequence
-	if data.MeasurementSequence == nil {
-		binary.BigEndian.PutUint16(result[16:18], 0xFFFF)
-	} else {
-		binary.BigEndian.PutUint16(result[16:18], *data.MeasurementSequence)
-	}
+// EncodeOptions controls how encoders handle values the format cannot represent.
+type EncodeOptions struct {
+	// Clamp saturates out-of-range values to the nearest representable value
+	// instead of returning a *RangeError.
+	Clamp bool
+}
 
-	// MAC address
-	if data.MACAddress == nil {
-		for i := 18; i < 24; i++ {
-			result[i] = 0xFF
-		}
-	} else {
-		copy(result[18:24], (*data.MACAddress)[:])
+// EncodeFormat5WithOptions encodes Format5Data like EncodeFormat5, with
+// out-of-range handling controlled by opts.
+func EncodeFormat5WithOptions(data *Format5Data, opts EncodeOptions) ([]byte, error) {
+	if data == nil {
+		return nil, ErrNilData
 	}
 
-	return result, nil
+	return format5Codec.encode(format5Reading(data), opts)
 }
 
 // EncodeFormat5ManufacturerData encodes Format5Data into manufacturer-specific data
@@ -2,6 +2,7 @@ package tag
 
 import (
 	"encoding/hex"
+	"errors"
 	"strings"
 	"testing"
 
@@ -627,6 +628,91 @@ func TestEncodeFormat5ManufacturerData_NilData(t *testing.T) {
 	}
 }
 
+// TestEncodeFormat5_Rounding tests that values are rounded to the nearest resolution step.
+func TestEncodeFormat5_Rounding(t *testing.T) {
+	temp := 24.3049 // 4860.98 steps, rounds up to 4861
+	hum := 53.49874 // 21399.496 steps, rounds down to 21399
+	tx := 3         // rounds to +4 dBm
+	accX := -0.0015 // -1.5 mG, rounds away from zero to -2
+
+	encoded, err := EncodeFormat5(&Format5Data{Temperature: &temp, Humidity: &hum, TxPower: &tx, AccelerationX: &accX})
+	if err != nil {
+		t.Fatalf("EncodeFormat5 failed: %v", err)
+	}
+
+	decoded, err := DecodeFormat5(encoded)
+	if err != nil {
+		t.Fatalf("DecodeFormat5 failed: %v", err)
+	}
+
+	if !floatEquals(*decoded.Temperature, 24.305, 1e-9) {
+		t.Errorf("Temperature = %v, want 24.305", *decoded.Temperature)
+	}
+	if !floatEquals(*decoded.Humidity, 53.4975, 1e-9) {
+		t.Errorf("Humidity = %v, want 53.4975", *decoded.Humidity)
+	}
+	if *decoded.TxPower != 4 {
+		t.Errorf("TxPower = %d, want 4", *decoded.TxPower)
+	}
+	if !floatEquals(*decoded.AccelerationX, -0.002, 1e-9) {
+		t.Errorf("AccelerationX = %v, want -0.002", *decoded.AccelerationX)
+	}
+}
+
+// TestEncodeFormat5_OutOfRange tests that unrepresentable values return a *RangeError,
+// or are saturated when clamping is enabled.
+func TestEncodeFormat5_OutOfRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    Format5Data
+		field   string
+		min     float64
+		max     float64
+		clamped string // hex of the clamped bytes at offset..offset+len
+		offset  int
+	}{
+		{name: "temperature high", data: Format5Data{Temperature: float64Ptr(200)}, field: "temperature", min: -163.835, max: 163.835, clamped: "7FFF", offset: 1},
+		{name: "temperature low", data: Format5Data{Temperature: float64Ptr(-163.84)}, field: "temperature", min: -163.835, max: 163.835, clamped: "8001", offset: 1},
+		{name: "humidity negative", data: Format5Data{Humidity: float64Ptr(-1)}, field: "humidity", min: 0, max: 163.835, clamped: "0000", offset: 3},
+		{name: "pressure low", data: Format5Data{Pressure: intPtr(49999)}, field: "pressure", min: 50000, max: 115534, clamped: "0000", offset: 5},
+		{name: "pressure high", data: Format5Data{Pressure: intPtr(115535)}, field: "pressure", min: 50000, max: 115534, clamped: "FFFE", offset: 5},
+		{name: "acceleration", data: Format5Data{AccelerationY: float64Ptr(40)}, field: "acceleration Y", min: -32.767, max: 32.767, clamped: "7FFF", offset: 9},
+		{name: "battery", data: Format5Data{BatteryVoltage: intPtr(1500)}, field: "battery voltage", min: 1600, max: 3646, clamped: "001F", offset: 13},
+		{name: "tx power", data: Format5Data{TxPower: intPtr(24)}, field: "TX power", min: -40, max: 20, clamped: "FFFE", offset: 13},
+		{name: "movement counter", data: Format5Data{MovementCounter: common.Uint8Ptr(255)}, field: "movement counter", min: 0, max: 254, clamped: "FE", offset: 15},
+		{name: "sequence", data: Format5Data{MeasurementSequence: common.Uint16Ptr(65535)}, field: "measurement sequence", min: 0, max: 65534, clamped: "FFFE", offset: 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := EncodeFormat5(&tt.data)
+			if !errors.Is(err, ErrOutOfRange) {
+				t.Fatalf("EncodeFormat5 error = %v, want ErrOutOfRange", err)
+			}
+
+			var rangeErr *RangeError
+			if !errors.As(err, &rangeErr) {
+				t.Fatalf("EncodeFormat5 error %T is not *RangeError", err)
+			}
+			if rangeErr.Field != tt.field {
+				t.Errorf("Field = %q, want %q", rangeErr.Field, tt.field)
+			}
+			if !floatEquals(rangeErr.Min, tt.min, 1e-9) || !floatEquals(rangeErr.Max, tt.max, 1e-9) {
+				t.Errorf("bounds = [%v, %v], want [%v, %v]", rangeErr.Min, rangeErr.Max, tt.min, tt.max)
+			}
+
+			encoded, err := EncodeFormat5WithOptions(&tt.data, EncodeOptions{Clamp: true})
+			if err != nil {
+				t.Fatalf("EncodeFormat5WithOptions(Clamp) failed: %v", err)
+			}
+			want, _ := hex.DecodeString(tt.clamped)
+			if got := encoded[tt.offset : tt.offset+len(want)]; !bytesEqual(got, want) {
+				t.Errorf("clamped bytes = %X, want %X", got, want)
+			}
+		})
+	}
+}
+
 // bytesEqual compares two byte slices for equality.
 func bytesEqual(a, b []byte) bool {
 	if len(a) != len(b) {
@@ -640,3 +726,125 @@ func bytesEqual(a, b []byte) bool {
 	return true
 }
 
+
+// TestDecodeFormat5Into tests decoding into a value-typed reading.
+func TestDecodeFormat5Into(t *testing.T) {
+	var r Format5Reading
+	if err := DecodeFormat5Into(mustDecodeHex(t, "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F"), &r); err != nil {
+		t.Fatalf("DecodeFormat5Into failed: %v", err)
+	}
+	if r.Valid != Format5AllFields || !r.Has(Format5Temperature|Format5MACAddress) {
+		t.Errorf("Valid = %b, want all fields", r.Valid)
+	}
+	if r.Temperature != 24.3 || r.Pressure != 100044 || r.TxPower != 4 || r.MeasurementSequence != 205 ||
+		r.MACAddress.String() != "CB:B8:33:4C:88:4F" {
+		t.Errorf("unexpected reading: %+v", r)
+	}
+
+	// Reusing the reading clears fields that are no longer available
+	if err := DecodeFormat5Into(mustDecodeHex(t, "058000FFFFFFFF800080008000FFFFFFFFFFFFFFFFFFFFFF"), &r); err != nil {
+		t.Fatalf("DecodeFormat5Into failed: %v", err)
+	}
+	if r != (Format5Reading{}) {
+		t.Errorf("expected zero reading for sentinels, got %+v", r)
+	}
+
+	// Errors leave the reading unchanged
+	r.Temperature = 1
+	if err := DecodeFormat5Into([]byte{0x05}, &r); !errors.Is(err, ErrInvalidLength) || r.Temperature != 1 {
+		t.Errorf("DecodeFormat5Into(short) = %v, reading %+v", err, r)
+	}
+}
+
+// TestFormat5Reading_Data tests that the pointer API matches the reading.
+func TestFormat5Reading_Data(t *testing.T) {
+	raw := mustDecodeHex(t, "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F")
+	var r Format5Reading
+	if err := DecodeFormat5Into(raw, &r); err != nil {
+		t.Fatal(err)
+	}
+	r.Valid &^= Format5Humidity
+
+	d := r.Data()
+	if d.Humidity != nil || d.Temperature == nil || *d.Temperature != r.Temperature {
+		t.Errorf("unexpected data: %+v", d)
+	}
+
+	encoded, err := EncodeFormat5(d)
+	if err != nil {
+		t.Fatalf("EncodeFormat5 failed: %v", err)
+	}
+	if encoded[3] != 0xFF || encoded[4] != 0xFF {
+		t.Errorf("humidity encoded as %X, want FFFF", encoded[3:5])
+	}
+}
+
+// TestDecodeFormat5Into_Allocs tests that decoding into a reading does not allocate.
+func TestDecodeFormat5Into_Allocs(t *testing.T) {
+	raw := mustDecodeHex(t, "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F")
+	var r Format5Reading
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := DecodeFormat5Into(raw, &r); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("DecodeFormat5Into allocated %v times per run, want 0", allocs)
+	}
+}
+
+// TestDecodeFormat5_Allocs tests that the pointer API allocates the result
+// and its values together, as a guard against per-field allocations.
+func TestDecodeFormat5_Allocs(t *testing.T) {
+	raw := mustDecodeHex(t, "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F")
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := DecodeFormat5(raw); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 1 {
+		t.Errorf("DecodeFormat5 allocated %v times per run, want 1", allocs)
+	}
+
+	var r Format5Reading
+	if err := DecodeFormat5Into(raw, &r); err != nil {
+		t.Fatal(err)
+	}
+	allocs = testing.AllocsPerRun(100, func() { _ = r.Data() })
+	if allocs != 1 {
+		t.Errorf("Format5Reading.Data allocated %v times per run, want 1", allocs)
+	}
+}
+
+func BenchmarkDecodeFormat5(b *testing.B) {
+	raw, _ := hex.DecodeString("0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F")
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := DecodeFormat5(raw); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFormat5Reading_Data(b *testing.B) {
+	raw, _ := hex.DecodeString("0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F")
+	var r Format5Reading
+	if err := DecodeFormat5Into(raw, &r); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = r.Data()
+	}
+}
+
+func BenchmarkDecodeFormat5Into(b *testing.B) {
+	raw, _ := hex.DecodeString("0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F")
+	var r Format5Reading
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := DecodeFormat5Into(raw, &r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
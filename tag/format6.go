@@ -0,0 +1,151 @@
+package tag
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Format 6 luminosity is transmitted as a logarithmic 8-bit code where
+// code 0 is 0 lux and code 254 is 65535 lux.
+const (
+	format6LuminosityMax   = 65535.0
+	format6LuminosityCodes = 254.0
+)
+
+// format6LuminosityDelta is the natural-log step between two luminosity codes.
+var format6LuminosityDelta = math.Log(format6LuminosityMax+1) / format6LuminosityCodes
+
+// Format 6 flag bits.
+const (
+	// Format6FlagCalibrationInProgress is set while the air quality sensors are calibrating.
+	Format6FlagCalibrationInProgress uint8 = 1 << 0
+
+	// format6FlagVOCLSB carries the least significant bit of the 9-bit VOC index.
+	format6FlagVOCLSB uint8 = 1 << 6
+
+	// format6FlagNOXLSB carries the least significant bit of the 9-bit NOx index.
+	format6FlagNOXLSB uint8 = 1 << 7
+)
+
+// Format6Data represents decoded Ruuvi Data Format 6 sensor data.
+// This format is broadcast by Ruuvi Air air quality monitors.
+type Format6Data struct {
+	Temperature         *float64 `json:"temperature_c"`        // Temperature in degrees Celsius
+	Humidity            *float64 `json:"humidity_percent"`     // Relative humidity in percent
+	Pressure            *int     `json:"pressure_pa"`          // Atmospheric pressure in Pascals
+	PM25                *float64 `json:"pm2_5_ug_m3"`          // PM2.5 particulate matter in µg/m³
+	CO2                 *int     `json:"co2_ppm"`              // CO2 concentration in ppm
+	VOCIndex            *int     `json:"voc_index"`            // VOC index (1-500)
+	NOXIndex            *int     `json:"nox_index"`            // NOx index (1-500)
+	Luminosity          *float64 `json:"luminosity_lux"`       // Luminosity in lux
+	MeasurementSequence *uint8   `json:"measurement_sequence"` // Measurement sequence number (0-255, wraps around)
+	Flags               uint8    `json:"flags"`                // Status flags, see Format6FlagCalibrationInProgress
+	MACSuffix           *[3]byte `json:"mac_suffix"`           // Lowest 3 bytes of the MAC address
+}
+
+// CalibrationInProgress reports whether the calibration flag is set.
+func (d *Format6Data) CalibrationInProgress() bool {
+	return d.Flags&Format6FlagCalibrationInProgress != 0
+}
+
+// MarshalJSON encodes the data with the MAC suffix in colon-separated hex
+// format, for example "4C:88:4F".
+func (d Format6Data) MarshalJSON() ([]byte, error) {
+	type plain Format6Data
+	aux := struct {
+		plain
+		MACSuffix *string `json:"mac_suffix"`
+	}{plain: plain(d)}
+
+	if d.MACSuffix != nil {
+		s := fmt.Sprintf("%02X:%02X:%02X", d.MACSuffix[0], d.MACSuffix[1], d.MACSuffix[2])
+		aux.MACSuffix = &s
+	}
+
+	return json.Marshal(aux)
+}
+
+// UnmarshalJSON decodes data encoded by MarshalJSON. The MAC suffix may be
+// colon-separated, dash-separated or plain hex.
+func (d *Format6Data) UnmarshalJSON(b []byte) error {
+	type plain Format6Data
+	aux := struct {
+		*plain
+		MACSuffix *string `json:"mac_suffix"`
+	}{plain: (*plain)(d)}
+
+	if err := unmarshalStrict(b, &aux); err != nil {
+		return err
+	}
+
+	d.MACSuffix = nil
+	if aux.MACSuffix != nil {
+		clean := strings.NewReplacer(":", "", "-", "").Replace(*aux.MACSuffix)
+		raw, err := hex.DecodeString(clean)
+		if err != nil || len(raw) != 3 {
+			return fmt.Errorf("invalid MAC suffix %q: want 3 hex bytes", *aux.MACSuffix)
+		}
+		var suffix [3]byte
+		copy(suffix[:], raw)
+		d.MACSuffix = &suffix
+	}
+
+	return nil
+}
+
+// format6Codec lays out Data Format 6. The least significant bits of the
+// 9-bit VOC and NOx indexes are stored in the flags byte.
+var format6Codec = formatCodec[Format6Data]{format: Format6, size: 20, bindings: []binding[Format6Data]{
+	{Field: formatByte, fill: byte(Format6)},
+	floatField(temperature005(1), func(d *Format6Data) **float64 { return &d.Temperature }),
+	floatField(humidity0025(3), func(d *Format6Data) **float64 { return &d.Humidity }),
+	intField(pressure50000(5), func(d *Format6Data) **int { return &d.Pressure }),
+	floatField(linear("pm2_5_ug_m3", "PM2.5", 7, 2, false, 0.1, 0, "µg/m³").withSentinel(0xFFFF), func(d *Format6Data) **float64 { return &d.PM25 }),
+	intField(linear("co2_ppm", "CO2", 9, 2, false, 1, 0, "ppm").withSentinel(0xFFFF), func(d *Format6Data) **int { return &d.CO2 }),
+	intField(linear("voc_index", "VOC index", 11, 1, false, 1, 0, "").withLSB(16, format6FlagVOCLSB).withSentinel(0x1FF), func(d *Format6Data) **int { return &d.VOCIndex }),
+	intField(linear("nox_index", "NOx index", 12, 1, false, 1, 0, "").withLSB(16, format6FlagNOXLSB).withSentinel(0x1FF), func(d *Format6Data) **int { return &d.NOXIndex }),
+	floatField(Field{
+		Name: "luminosity_lux", Label: "luminosity", Kind: FieldLogarithmic, Unit: "lx", Offset: 13, Size: 1,
+		Scale: format6LuminosityDelta, HasSentinel: true, Sentinel: 0xFF,
+	}, func(d *Format6Data) **float64 { return &d.Luminosity }),
+	{Field: raw("reserved", "reserved", 14, 1), fill: 0xFF},
+	intField(linear("measurement_sequence", "measurement sequence", 15, 1, false, 1, 0, ""), func(d *Format6Data) **uint8 { return &d.MeasurementSequence }),
+	flagsField(flags(16, format6FlagVOCLSB|format6FlagNOXLSB), func(d *Format6Data) *uint8 { return &d.Flags }),
+	macSuffixField(macAddress("mac_suffix", "MAC suffix", 17, 3), func(d *Format6Data) **[3]byte { return &d.MACSuffix }),
+}}
+
+// DecodeFormat6 decodes Ruuvi Data Format 6 from raw bytes.
+// The input must be exactly 20 bytes: 1 byte format ID + 19 bytes data.
+// Returns an error if the data is invalid or not Format 6.
+func DecodeFormat6(data []byte) (*Format6Data, error) {
+	if err := format6Codec.validate(data); err != nil {
+		return nil, err
+	}
+
+	result := &Format6Data{}
+	format6Codec.decode(data, result)
+
+	return result, nil
+}
+
+// EncodeFormat6 encodes Format6Data into raw bytes suitable for BLE advertisement payload.
+//
+// Returns exactly 20 bytes: 1 byte format ID (0x06) + 19 bytes data payload.
+//
+// Fields that are nil or NaN are encoded using the appropriate "not available" sentinel
+// values as defined in the Ruuvi Data Format 6 specification:
+//   - Temperature: 0x8000 (-32768)
+//   - Humidity, Pressure, PM2.5, CO2: 0xFFFF (65535)
+//   - VOC and NOx index: 0x1FF (511, in the 9-bit field)
+//   - Luminosity: 0xFF (255)
+//   - MAC suffix: all 0xFF bytes
+//
+// A nil measurement sequence is encoded as 0, since the field has no sentinel value.
+// Luminosity is saturated to 0-65535 lux; other values the format cannot
+// represent return a *RangeError.
+func EncodeFormat6(data *Format6Data) ([]byte, error) {
+	return format6Codec.encode(data, EncodeOptions{})
+}
@@ -0,0 +1,183 @@
+package tag
+
+import (
+	"encoding/hex"
+	"math"
+	"testing"
+)
+
+// TestDecodeFormat6_ValidData tests decoding with valid data from the official spec.
+func TestDecodeFormat6_ValidData(t *testing.T) {
+	raw, err := hex.DecodeString("06170C5668C79E007000C90501D9FFCD004C884F")
+	if err != nil {
+		t.Fatalf("Failed to decode hex: %v", err)
+	}
+
+	data, err := DecodeFormat6(raw)
+	if err != nil {
+		t.Fatalf("DecodeFormat6 failed: %v", err)
+	}
+
+	if data.Temperature == nil || !floatEquals(*data.Temperature, 29.5, 0.001) {
+		t.Errorf("Temperature = %v, want 29.5", data.Temperature)
+	}
+	if data.Humidity == nil || !floatEquals(*data.Humidity, 55.3, 0.001) {
+		t.Errorf("Humidity = %v, want 55.3", data.Humidity)
+	}
+	if data.Pressure == nil || *data.Pressure != 101102 {
+		t.Errorf("Pressure = %v, want 101102", data.Pressure)
+	}
+	if data.PM25 == nil || !floatEquals(*data.PM25, 11.2, 0.001) {
+		t.Errorf("PM25 = %v, want 11.2", data.PM25)
+	}
+	if data.CO2 == nil || *data.CO2 != 201 {
+		t.Errorf("CO2 = %v, want 201", data.CO2)
+	}
+	if data.VOCIndex == nil || *data.VOCIndex != 10 {
+		t.Errorf("VOCIndex = %v, want 10", data.VOCIndex)
+	}
+	if data.NOXIndex == nil || *data.NOXIndex != 2 {
+		t.Errorf("NOXIndex = %v, want 2", data.NOXIndex)
+	}
+	if data.Luminosity == nil || !floatEquals(*data.Luminosity, 13026, 5) {
+		t.Errorf("Luminosity = %v, want ~13026", data.Luminosity)
+	}
+	if data.MeasurementSequence == nil || *data.MeasurementSequence != 205 {
+		t.Errorf("MeasurementSequence = %v, want 205", data.MeasurementSequence)
+	}
+	if data.CalibrationInProgress() {
+		t.Error("CalibrationInProgress() = true, want false")
+	}
+	if data.MACSuffix == nil || *data.MACSuffix != [3]byte{0x4C, 0x88, 0x4F} {
+		t.Errorf("MACSuffix = %v, want 4C:88:4F", data.MACSuffix)
+	}
+}
+
+// TestDecodeFormat6_InvalidValues tests that sentinel values decode to nil.
+func TestDecodeFormat6_InvalidValues(t *testing.T) {
+	raw, err := hex.DecodeString("068000FFFFFFFFFFFFFFFFFFFFFFFF00C0FFFFFF")
+	if err != nil {
+		t.Fatalf("Failed to decode hex: %v", err)
+	}
+
+	data, err := DecodeFormat6(raw)
+	if err != nil {
+		t.Fatalf("DecodeFormat6 failed: %v", err)
+	}
+
+	if data.Temperature != nil || data.Humidity != nil || data.Pressure != nil {
+		t.Error("environmental fields should be nil for invalid values")
+	}
+	if data.PM25 != nil || data.CO2 != nil || data.VOCIndex != nil || data.NOXIndex != nil {
+		t.Error("air quality fields should be nil for invalid values")
+	}
+	if data.Luminosity != nil {
+		t.Error("Luminosity should be nil for invalid value")
+	}
+	if data.MACSuffix != nil {
+		t.Error("MACSuffix should be nil for invalid value")
+	}
+	if data.Flags != 0 {
+		t.Errorf("Flags = 0x%02X, want VOC/NOx LSBs stripped", data.Flags)
+	}
+}
+
+// TestDecodeFormat6_CalibrationFlag tests the calibration-in-progress flag.
+func TestDecodeFormat6_CalibrationFlag(t *testing.T) {
+	raw, err := hex.DecodeString("06170C5668C79E007000C90501D9FFCD014C884F")
+	if err != nil {
+		t.Fatalf("Failed to decode hex: %v", err)
+	}
+
+	data, err := DecodeFormat6(raw)
+	if err != nil {
+		t.Fatalf("DecodeFormat6 failed: %v", err)
+	}
+
+	if !data.CalibrationInProgress() {
+		t.Error("CalibrationInProgress() = false, want true")
+	}
+}
+
+// TestDecodeFormat6_Errors tests error conditions.
+func TestDecodeFormat6_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "too short", data: []byte{0x06, 0x17, 0x0C}},
+		{name: "too long", data: make([]byte, 21)},
+		{name: "wrong format", data: make([]byte, 20)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeFormat6(tt.data); err == nil {
+				t.Error("DecodeFormat6() error = nil, want error")
+			}
+		})
+	}
+}
+
+// TestEncodeFormat6_RoundTrip tests that encode-decode is bidirectional.
+func TestEncodeFormat6_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+	}{
+		{name: "valid data", hex: "06170C5668C79E007000C90501D9FFCD004C884F"},
+		{name: "odd indexes", hex: "06170C5668C79E007000C90501D9FFCDC14C884F"},
+		{name: "invalid values", hex: "068000FFFFFFFFFFFFFFFFFFFFFFFF00C0FFFFFF"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := hex.DecodeString(tt.hex)
+			if err != nil {
+				t.Fatalf("Failed to decode hex: %v", err)
+			}
+
+			decoded, err := DecodeFormat6(raw)
+			if err != nil {
+				t.Fatalf("DecodeFormat6 failed: %v", err)
+			}
+
+			encoded, err := EncodeFormat6(decoded)
+			if err != nil {
+				t.Fatalf("EncodeFormat6 failed: %v", err)
+			}
+
+			if !bytesEqual(encoded, raw) {
+				t.Errorf("Round trip failed:\ngot  %X\nwant %X", encoded, raw)
+			}
+		})
+	}
+}
+
+// TestEncodeFormat6_Luminosity tests the logarithmic luminosity encoding limits.
+func TestEncodeFormat6_Luminosity(t *testing.T) {
+	for _, lux := range []float64{0, -5, 65535, 1e9, math.NaN()} {
+		encoded, err := EncodeFormat6(&Format6Data{Luminosity: float64Ptr(lux)})
+		if err != nil {
+			t.Fatalf("EncodeFormat6 failed: %v", err)
+		}
+
+		var want byte
+		switch {
+		case math.IsNaN(lux):
+			want = 0xFF
+		case lux >= 65535:
+			want = 254
+		}
+		if encoded[13] != want {
+			t.Errorf("luminosity %v encoded as %d, want %d", lux, encoded[13], want)
+		}
+	}
+}
+
+// TestEncodeFormat6_NilData tests encoding with nil input.
+func TestEncodeFormat6_NilData(t *testing.T) {
+	if _, err := EncodeFormat6(nil); err == nil {
+		t.Error("EncodeFormat6(nil) should return error")
+	}
+}
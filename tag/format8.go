@@ -0,0 +1,179 @@
+package tag
+
+import (
+	"crypto/aes"
+	"fmt"
+
+	"github.com/marcgeld/ruuvi/common"
+)
+
+// Format8KeySize is the size of a Data Format 8 AES-128 key in bytes.
+const Format8KeySize = 16
+
+// Format8Data represents decoded RuuviTag Data Format 8 (encrypted environmental) sensor data.
+type Format8Data struct {
+	Temperature         *float64           `json:"temperature_c"`        // Temperature in degrees Celsius
+	Humidity            *float64           `json:"humidity_percent"`     // Relative humidity in percent
+	Pressure            *int               `json:"pressure_pa"`          // Atmospheric pressure in Pascals
+	BatteryVoltage      *int               `json:"battery_voltage_mv"`   // Battery voltage in millivolts
+	TxPower             *int               `json:"tx_power_dbm"`         // TX power in dBm
+	MovementCounter     *uint8             `json:"movement_counter"`     // Movement counter (0-254)
+	MeasurementSequence *uint16            `json:"measurement_sequence"` // Measurement sequence number (0-65534)
+	MACAddress          *common.MACAddress `json:"mac_address"`          // 48-bit MAC address
+}
+
+// format8Codec lays out Data Format 8 as transmitted. Only the MAC address
+// is sent in the clear; format8PlainCodec lays out the encrypted block.
+var format8Codec = formatCodec[Format8Data]{format: Format8, size: 24, bindings: []binding[Format8Data]{
+	{Field: formatByte, fill: byte(Format8)},
+	{Field: raw("ciphertext", "ciphertext", 1, 16)},
+	{Field: raw("crc", "CRC", 17, 1)},
+	macField(macAddress("mac_address", "MAC address", 18, 6), func(d *Format8Data) **common.MACAddress { return &d.MACAddress }),
+}}
+
+// format8PlainCodec lays out the decrypted Data Format 8 block, which holds
+// the Data Format 5 fields without acceleration. Reserved bytes are zeros.
+var format8PlainCodec = formatCodec[Format8Data]{format: Format8, size: 16, bindings: []binding[Format8Data]{
+	floatField(temperature005(0), func(d *Format8Data) **float64 { return &d.Temperature }),
+	floatField(humidity0025(2), func(d *Format8Data) **float64 { return &d.Humidity }),
+	intField(pressure50000(4), func(d *Format8Data) **int { return &d.Pressure }),
+	intField(rawv2BatteryVoltage(6), func(d *Format8Data) **int { return &d.BatteryVoltage }),
+	intField(rawv2TxPower(6), func(d *Format8Data) **int { return &d.TxPower }),
+	intField(rawv2MovementCounter(8), func(d *Format8Data) **uint8 { return &d.MovementCounter }),
+	intField(rawv2Sequence(9), func(d *Format8Data) **uint16 { return &d.MeasurementSequence }),
+	{Field: raw("reserved", "reserved", 11, 5)},
+}}
+
+// DecodeFormat8 decodes RuuviTag Data Format 8 (encrypted) from raw bytes using the given AES-128 key.
+// The input must be exactly 24 bytes: 1 byte format ID + 16 bytes encrypted data
+// + 1 byte CRC8 + 6 bytes MAC address.
+// Returns a *CRCError if the decrypted data fails the checksum.
+func DecodeFormat8(data []byte, key []byte) (*Format8Data, error) {
+	if err := format8Codec.validate(data); err != nil {
+		return nil, err
+	}
+
+	if len(key) != Format8KeySize {
+		return nil, fmt.Errorf("%w: must be %d bytes, got %d", ErrInvalidKey, Format8KeySize, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("format 8 cipher: %w", err)
+	}
+
+	// AES-128 in ECB mode over a single block
+	plain := make([]byte, aes.BlockSize)
+	block.Decrypt(plain, data[1:17])
+
+	if crc := crc8(plain); crc != data[17] {
+		return nil, &CRCError{Want: data[17], Got: crc}
+	}
+
+	result := &Format8Data{}
+	format8PlainCodec.decode(plain, result)
+	format8Codec.decode(data, result)
+
+	return result, nil
+}
+
+// DecodeFormat8WithKeyStore decodes RuuviTag Data Format 8 (encrypted) from raw bytes,
+// looking up the decryption key by the MAC address transmitted in the payload.
+// Returns a *KeyNotFoundError if the key store is nil or has no key for the tag.
+func DecodeFormat8WithKeyStore(data []byte, keys KeyStore) (*Format8Data, error) {
+	if err := format8Codec.validate(data); err != nil {
+		return nil, err
+	}
+
+	var mac common.MACAddress
+	copy(mac[:], data[18:24])
+
+	if keys == nil {
+		return nil, &KeyNotFoundError{MAC: mac}
+	}
+
+	key, ok := keys.Key(mac)
+	if !ok {
+		return nil, &KeyNotFoundError{MAC: mac}
+	}
+
+	return DecodeFormat8(data, key)
+}
+
+// EncodeFormat8 encodes and encrypts Format8Data with the given AES-128 key into
+// raw bytes suitable for BLE advertisement payload.
+//
+// Returns exactly 24 bytes: 1 byte format ID (0x08) + 16 bytes encrypted data
+// + 1 byte CRC8 + 6 bytes MAC address.
+//
+// Scaling, rounding, range checks and "not available" sentinel values follow
+// Data Format 5; see EncodeFormat5 for details. Reserved bytes are encoded as zeros.
+func EncodeFormat8(data *Format8Data, key []byte) ([]byte, error) {
+	if data == nil {
+		return nil, ErrNilData
+	}
+
+	if len(key) != Format8KeySize {
+		return nil, fmt.Errorf("%w: must be %d bytes, got %d", ErrInvalidKey, Format8KeySize, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("format 8 cipher: %w", err)
+	}
+
+	plain, err := format8PlainCodec.encode(data, EncodeOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	// The transmitted fields other than the MAC address are filled in below
+	result, err := format8Codec.encode(data, EncodeOptions{})
+	if err != nil {
+		return nil, err
+	}
+	block.Encrypt(result[1:17], plain)
+	result[17] = crc8(plain)
+
+	return result, nil
+}
+
+// EncodeFormat8WithKeyStore encodes Format8Data like EncodeFormat8, using the
+// key that keys holds for data.MACAddress. Returns a *KeyNotFoundError if no
+// key is known, and ErrKeyNotFound if the data has no MAC address.
+func EncodeFormat8WithKeyStore(data *Format8Data, keys KeyStore) ([]byte, error) {
+	if data == nil {
+		return nil, ErrNilData
+	}
+
+	if data.MACAddress == nil {
+		return nil, fmt.Errorf("%w: format 8 data has no MAC address", ErrKeyNotFound)
+	}
+
+	if keys == nil {
+		return nil, &KeyNotFoundError{MAC: *data.MACAddress}
+	}
+
+	key, ok := keys.Key(*data.MACAddress)
+	if !ok {
+		return nil, &KeyNotFoundError{MAC: *data.MACAddress}
+	}
+
+	return EncodeFormat8(data, key)
+}
+
+// crc8 computes the CRC-8 checksum (polynomial 0x07, initial value 0x00) used by Data Format 8.
+func crc8(data []byte) uint8 {
+	var crc uint8
+	for _, b := range data {
+		crc ^= b
+		for range 8 {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
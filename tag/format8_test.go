@@ -0,0 +1,178 @@
+package tag
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/marcgeld/ruuvi/common"
+)
+
+// format8TestKey and format8TestVector are the Format 5 reference values
+// encrypted as Format 8 with a known key.
+const (
+	format8TestKey    = "00112233445566778899AABBCCDDEEFF"
+	format8TestVector = "087D645F54DE0E24082C4F56A21C7BEC82BCCBB8334C884F"
+)
+
+var format8TestMAC = common.MACAddress{0xCB, 0xB8, 0x33, 0x4C, 0x88, 0x4F}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("Failed to decode hex: %v", err)
+	}
+	return b
+}
+
+// TestDecodeFormat8_ValidData tests decrypting and decoding a Format 8 payload.
+func TestDecodeFormat8_ValidData(t *testing.T) {
+	data, err := DecodeFormat8(mustDecodeHex(t, format8TestVector), mustDecodeHex(t, format8TestKey))
+	if err != nil {
+		t.Fatalf("DecodeFormat8 failed: %v", err)
+	}
+
+	if data.Temperature == nil || !floatEquals(*data.Temperature, 24.3, 0.001) {
+		t.Errorf("Temperature = %v, want 24.3", data.Temperature)
+	}
+	if data.Humidity == nil || !floatEquals(*data.Humidity, 53.49, 0.01) {
+		t.Errorf("Humidity = %v, want 53.49", data.Humidity)
+	}
+	if data.Pressure == nil || *data.Pressure != 100044 {
+		t.Errorf("Pressure = %v, want 100044", data.Pressure)
+	}
+	if data.BatteryVoltage == nil || *data.BatteryVoltage != 2977 {
+		t.Errorf("BatteryVoltage = %v, want 2977", data.BatteryVoltage)
+	}
+	if data.TxPower == nil || *data.TxPower != 4 {
+		t.Errorf("TxPower = %v, want 4", data.TxPower)
+	}
+	if data.MovementCounter == nil || *data.MovementCounter != 66 {
+		t.Errorf("MovementCounter = %v, want 66", data.MovementCounter)
+	}
+	if data.MeasurementSequence == nil || *data.MeasurementSequence != 205 {
+		t.Errorf("MeasurementSequence = %v, want 205", data.MeasurementSequence)
+	}
+	if data.MACAddress == nil || *data.MACAddress != format8TestMAC {
+		t.Errorf("MACAddress = %v, want %v", data.MACAddress, format8TestMAC)
+	}
+}
+
+// TestDecodeFormat8_WrongKey tests that a wrong key is reported as a CRC error.
+func TestDecodeFormat8_WrongKey(t *testing.T) {
+	_, err := DecodeFormat8(mustDecodeHex(t, format8TestVector), make([]byte, Format8KeySize))
+
+	var crcErr *CRCError
+	if !errors.As(err, &crcErr) {
+		t.Fatalf("DecodeFormat8() error = %v, want *CRCError", err)
+	}
+}
+
+// TestDecodeFormat8_Errors tests error conditions.
+func TestDecodeFormat8_Errors(t *testing.T) {
+	key := mustDecodeHex(t, format8TestKey)
+
+	tests := []struct {
+		name string
+		data []byte
+		key  []byte
+	}{
+		{name: "too short", data: []byte{0x08, 0x12, 0xFC}, key: key},
+		{name: "too long", data: make([]byte, 25), key: key},
+		{name: "wrong format", data: make([]byte, 24), key: key},
+		{name: "short key", data: mustDecodeHex(t, format8TestVector), key: key[:8]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeFormat8(tt.data, tt.key); err == nil {
+				t.Error("DecodeFormat8() error = nil, want error")
+			}
+		})
+	}
+}
+
+// TestDecodeFormat8WithKeyStore tests key lookup by MAC address.
+func TestDecodeFormat8WithKeyStore(t *testing.T) {
+	raw := mustDecodeHex(t, format8TestVector)
+
+	var notFound *KeyNotFoundError
+	if _, err := DecodeFormat8WithKeyStore(raw, nil); !errors.As(err, &notFound) {
+		t.Fatalf("nil key store error = %v, want *KeyNotFoundError", err)
+	}
+
+	keys := NewMemoryKeyStore()
+	if _, err := DecodeFormat8WithKeyStore(raw, keys); !errors.As(err, &notFound) {
+		t.Fatalf("empty key store error = %v, want *KeyNotFoundError", err)
+	}
+	if notFound.MAC != format8TestMAC {
+		t.Errorf("KeyNotFoundError.MAC = %v, want %v", notFound.MAC, format8TestMAC)
+	}
+
+	if err := keys.Set(format8TestMAC, mustDecodeHex(t, format8TestKey)); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	data, err := DecodeFormat8WithKeyStore(raw, keys)
+	if err != nil {
+		t.Fatalf("DecodeFormat8WithKeyStore failed: %v", err)
+	}
+	if data.MeasurementSequence == nil || *data.MeasurementSequence != 205 {
+		t.Errorf("MeasurementSequence = %v, want 205", data.MeasurementSequence)
+	}
+}
+
+// TestDecode_Format8 tests that Decode uses DefaultKeyStore.
+func TestDecode_Format8(t *testing.T) {
+	orig := DefaultKeyStore
+	defer func() { DefaultKeyStore = orig }()
+
+	raw := mustDecodeHex(t, format8TestVector)
+
+	DefaultKeyStore = nil
+	var notFound *KeyNotFoundError
+	if _, err := Decode(raw); !errors.As(err, &notFound) {
+		t.Fatalf("Decode() error = %v, want *KeyNotFoundError", err)
+	}
+
+	keys := NewMemoryKeyStore()
+	if err := keys.Set(format8TestMAC, mustDecodeHex(t, format8TestKey)); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	DefaultKeyStore = keys
+
+	decoded, err := Decode(raw)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if decoded.Format != Format8 || decoded.Format8 == nil {
+		t.Fatalf("Decode() = %+v, want Format8 data", decoded)
+	}
+}
+
+// TestEncodeFormat8_RoundTrip tests that encrypt-decrypt is bidirectional.
+func TestEncodeFormat8_RoundTrip(t *testing.T) {
+	key := mustDecodeHex(t, format8TestKey)
+	raw := mustDecodeHex(t, format8TestVector)
+
+	decoded, err := DecodeFormat8(raw, key)
+	if err != nil {
+		t.Fatalf("DecodeFormat8 failed: %v", err)
+	}
+
+	encoded, err := EncodeFormat8(decoded, key)
+	if err != nil {
+		t.Fatalf("EncodeFormat8 failed: %v", err)
+	}
+
+	if !bytesEqual(encoded, raw) {
+		t.Errorf("Round trip failed:\ngot  %X\nwant %X", encoded, raw)
+	}
+}
+
+// TestEncodeFormat8_NilData tests encoding with nil input.
+func TestEncodeFormat8_NilData(t *testing.T) {
+	if _, err := EncodeFormat8(nil, mustDecodeHex(t, format8TestKey)); err == nil {
+		t.Error("EncodeFormat8(nil) should return error")
+	}
+}
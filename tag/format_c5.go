@@ -0,0 +1,56 @@
+package tag
+
+import "github.com/marcgeld/ruuvi/common"
+
+// FormatC5Data represents decoded RuuviTag Data Format C5 (cut-down RAWv2) sensor data.
+// This format is Format 5 without the acceleration fields, used by low-power firmware profiles.
+type FormatC5Data struct {
+	Temperature         *float64           `json:"temperature_c"`        // Temperature in degrees Celsius
+	Humidity            *float64           `json:"humidity_percent"`     // Relative humidity in percent
+	Pressure            *int               `json:"pressure_pa"`          // Atmospheric pressure in Pascals
+	BatteryVoltage      *int               `json:"battery_voltage_mv"`   // Battery voltage in millivolts
+	TxPower             *int               `json:"tx_power_dbm"`         // TX power in dBm
+	MovementCounter     *uint8             `json:"movement_counter"`     // Movement counter (0-254)
+	MeasurementSequence *uint16            `json:"measurement_sequence"` // Measurement sequence number (0-65534)
+	MACAddress          *common.MACAddress `json:"mac_address"`          // 48-bit MAC address
+}
+
+// formatC5Codec lays out Data Format C5, which is Data Format 5 without the
+// acceleration fields.
+var formatC5Codec = formatCodec[FormatC5Data]{format: FormatC5, size: 18, bindings: []binding[FormatC5Data]{
+	{Field: formatByte, fill: byte(FormatC5)},
+	floatField(temperature005(1), func(d *FormatC5Data) **float64 { return &d.Temperature }),
+	floatField(humidity0025(3), func(d *FormatC5Data) **float64 { return &d.Humidity }),
+	intField(pressure50000(5), func(d *FormatC5Data) **int { return &d.Pressure }),
+	intField(rawv2BatteryVoltage(7), func(d *FormatC5Data) **int { return &d.BatteryVoltage }),
+	intField(rawv2TxPower(7), func(d *FormatC5Data) **int { return &d.TxPower }),
+	intField(rawv2MovementCounter(9), func(d *FormatC5Data) **uint8 { return &d.MovementCounter }),
+	intField(rawv2Sequence(10), func(d *FormatC5Data) **uint16 { return &d.MeasurementSequence }),
+	macField(macAddress("mac_address", "MAC address", 12, 6), func(d *FormatC5Data) **common.MACAddress { return &d.MACAddress }),
+}}
+
+// DecodeFormatC5 decodes RuuviTag Data Format C5 (cut-down RAWv2) from raw bytes.
+// The input must be exactly 18 bytes: 1 byte format ID + 17 bytes data.
+// Returns an error if the data is invalid or not Format C5.
+//
+// Field scaling and sentinel values are identical to Data Format 5.
+func DecodeFormatC5(data []byte) (*FormatC5Data, error) {
+	if err := formatC5Codec.validate(data); err != nil {
+		return nil, err
+	}
+
+	result := &FormatC5Data{}
+	formatC5Codec.decode(data, result)
+
+	return result, nil
+}
+
+// EncodeFormatC5 encodes FormatC5Data into raw bytes suitable for BLE advertisement payload.
+//
+// Returns exactly 18 bytes: 1 byte format ID (0xC5) + 17 bytes data payload.
+//
+// Scaling, rounding, range checks and "not available" sentinel values follow
+// Data Format 5; see EncodeFormat5 for details.
+func EncodeFormatC5(data *FormatC5Data) ([]byte, error) {
+	return formatC5Codec.encode(data, EncodeOptions{})
+}
@@ -0,0 +1,137 @@
+package tag
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/marcgeld/ruuvi/common"
+)
+
+// TestDecodeFormatC5_ValidData tests decoding with valid data from the official spec.
+func TestDecodeFormatC5_ValidData(t *testing.T) {
+	raw, err := hex.DecodeString("C512FC5394C37CAC364200CDCBB8334C884F")
+	if err != nil {
+		t.Fatalf("Failed to decode hex: %v", err)
+	}
+
+	data, err := DecodeFormatC5(raw)
+	if err != nil {
+		t.Fatalf("DecodeFormatC5 failed: %v", err)
+	}
+
+	if data.Temperature == nil || !floatEquals(*data.Temperature, 24.3, 0.001) {
+		t.Errorf("Temperature = %v, want 24.3", data.Temperature)
+	}
+	if data.Humidity == nil || !floatEquals(*data.Humidity, 53.49, 0.01) {
+		t.Errorf("Humidity = %v, want 53.49", data.Humidity)
+	}
+	if data.Pressure == nil || *data.Pressure != 100044 {
+		t.Errorf("Pressure = %v, want 100044", data.Pressure)
+	}
+	if data.BatteryVoltage == nil || *data.BatteryVoltage != 2977 {
+		t.Errorf("BatteryVoltage = %v, want 2977", data.BatteryVoltage)
+	}
+	if data.TxPower == nil || *data.TxPower != 4 {
+		t.Errorf("TxPower = %v, want 4", data.TxPower)
+	}
+	if data.MovementCounter == nil || *data.MovementCounter != 66 {
+		t.Errorf("MovementCounter = %v, want 66", data.MovementCounter)
+	}
+	if data.MeasurementSequence == nil || *data.MeasurementSequence != 205 {
+		t.Errorf("MeasurementSequence = %v, want 205", data.MeasurementSequence)
+	}
+
+	wantMAC := common.MACAddress{0xCB, 0xB8, 0x33, 0x4C, 0x88, 0x4F}
+	if data.MACAddress == nil || *data.MACAddress != wantMAC {
+		t.Errorf("MACAddress = %v, want %v", data.MACAddress, wantMAC)
+	}
+}
+
+// TestDecodeFormatC5_InvalidValues tests that sentinel values decode to nil.
+func TestDecodeFormatC5_InvalidValues(t *testing.T) {
+	raw, err := hex.DecodeString("C58000FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF")
+	if err != nil {
+		t.Fatalf("Failed to decode hex: %v", err)
+	}
+
+	data, err := DecodeFormatC5(raw)
+	if err != nil {
+		t.Fatalf("DecodeFormatC5 failed: %v", err)
+	}
+
+	if data.Temperature != nil || data.Humidity != nil || data.Pressure != nil {
+		t.Error("environmental fields should be nil for invalid values")
+	}
+	if data.BatteryVoltage != nil || data.TxPower != nil {
+		t.Error("power fields should be nil for invalid values")
+	}
+	if data.MovementCounter != nil || data.MeasurementSequence != nil {
+		t.Error("counter fields should be nil for invalid values")
+	}
+	if data.MACAddress != nil {
+		t.Error("MACAddress should be nil for invalid value")
+	}
+}
+
+// TestDecodeFormatC5_Errors tests error conditions.
+func TestDecodeFormatC5_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "too short", data: []byte{0xC5, 0x12, 0xFC}},
+		{name: "too long", data: make([]byte, 19)},
+		{name: "wrong format", data: make([]byte, 18)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeFormatC5(tt.data); err == nil {
+				t.Error("DecodeFormatC5() error = nil, want error")
+			}
+		})
+	}
+}
+
+// TestEncodeFormatC5_RoundTrip tests that encode-decode is bidirectional.
+func TestEncodeFormatC5_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+	}{
+		{name: "valid data", hex: "C512FC5394C37CAC364200CDCBB8334C884F"},
+		{name: "maximum values", hex: "C57FFFFFFEFFFEFFDEFEFFFECBB8334C884F"},
+		{name: "minimum values", hex: "C58001000000000000000000CBB8334C884F"},
+		{name: "invalid values", hex: "C58000FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := hex.DecodeString(tt.hex)
+			if err != nil {
+				t.Fatalf("Failed to decode hex: %v", err)
+			}
+
+			decoded, err := DecodeFormatC5(raw)
+			if err != nil {
+				t.Fatalf("DecodeFormatC5 failed: %v", err)
+			}
+
+			encoded, err := EncodeFormatC5(decoded)
+			if err != nil {
+				t.Fatalf("EncodeFormatC5 failed: %v", err)
+			}
+
+			if !bytesEqual(encoded, raw) {
+				t.Errorf("Round trip failed:\ngot  %X\nwant %X", encoded, raw)
+			}
+		})
+	}
+}
+
+// TestEncodeFormatC5_NilData tests encoding with nil input.
+func TestEncodeFormatC5_NilData(t *testing.T) {
+	if _, err := EncodeFormatC5(nil); err == nil {
+		t.Error("EncodeFormatC5(nil) should return error")
+	}
+}
@@ -0,0 +1,105 @@
+package tag
+
+import "github.com/marcgeld/ruuvi/common"
+
+// Format E1 flag bits.
+const (
+	// FormatE1FlagCalibrationInProgress is set while the air quality sensors are calibrating.
+	FormatE1FlagCalibrationInProgress uint8 = 1 << 0
+
+	// formatE1FlagSoundInstantLSB carries the least significant bit of the instant sound level.
+	formatE1FlagSoundInstantLSB uint8 = 1 << 3
+
+	// formatE1FlagSoundAverageLSB carries the least significant bit of the average sound level.
+	formatE1FlagSoundAverageLSB uint8 = 1 << 4
+
+	// formatE1FlagSoundPeakLSB carries the least significant bit of the peak sound level.
+	formatE1FlagSoundPeakLSB uint8 = 1 << 5
+
+	// formatE1FlagVOCLSB carries the least significant bit of the 9-bit VOC index.
+	formatE1FlagVOCLSB uint8 = 1 << 6
+
+	// formatE1FlagNOXLSB carries the least significant bit of the 9-bit NOx index.
+	formatE1FlagNOXLSB uint8 = 1 << 7
+
+	// formatE1LSBFlags masks all flag bits that carry value LSBs.
+	formatE1LSBFlags = formatE1FlagSoundInstantLSB | formatE1FlagSoundAverageLSB |
+		formatE1FlagSoundPeakLSB | formatE1FlagVOCLSB | formatE1FlagNOXLSB
+)
+
+// FormatE1Data represents decoded Ruuvi Extended Data Format E1 sensor data.
+// This format is sent over BLE extended advertising and carries the full
+// Ruuvi Air sensor set.
+type FormatE1Data struct {
+	Temperature         *float64           `json:"temperature_c"`        // Temperature in degrees Celsius
+	Humidity            *float64           `json:"humidity_percent"`     // Relative humidity in percent
+	Pressure            *int               `json:"pressure_pa"`          // Atmospheric pressure in Pascals
+	PM10                *float64           `json:"pm1_0_ug_m3"`          // PM1.0 particulate matter in µg/m³
+	PM25                *float64           `json:"pm2_5_ug_m3"`          // PM2.5 particulate matter in µg/m³
+	PM40                *float64           `json:"pm4_0_ug_m3"`          // PM4.0 particulate matter in µg/m³
+	PM100               *float64           `json:"pm10_0_ug_m3"`         // PM10 particulate matter in µg/m³
+	CO2                 *int               `json:"co2_ppm"`              // CO2 concentration in ppm
+	VOCIndex            *int               `json:"voc_index"`            // VOC index (1-500)
+	NOXIndex            *int               `json:"nox_index"`            // NOx index (1-500)
+	Luminosity          *float64           `json:"luminosity_lux"`       // Luminosity in lux
+	SoundInstant        *float64           `json:"sound_instant_dba"`    // Instant sound level in dBA
+	SoundAverage        *float64           `json:"sound_average_dba"`    // Average sound level in dBA
+	SoundPeak           *float64           `json:"sound_peak_dba"`       // Peak sound level in dBA
+	MeasurementSequence *uint32            `json:"measurement_sequence"` // Measurement sequence number (0-16777214)
+	Flags               uint8              `json:"flags"`                // Status flags, see FormatE1FlagCalibrationInProgress
+	MACAddress          *common.MACAddress `json:"mac_address"`          // 48-bit MAC address
+}
+
+// CalibrationInProgress reports whether the calibration flag is set.
+func (d *FormatE1Data) CalibrationInProgress() bool {
+	return d.Flags&FormatE1FlagCalibrationInProgress != 0
+}
+
+// formatE1Codec lays out Data Format E1. The least significant bits of the
+// 9-bit VOC and NOx indexes and sound levels are stored in the flags byte.
+var formatE1Codec = formatCodec[FormatE1Data]{format: FormatE1, size: 40, bindings: []binding[FormatE1Data]{
+	{Field: formatByte, fill: byte(FormatE1)},
+	floatField(temperature005(1), func(d *FormatE1Data) **float64 { return &d.Temperature }),
+	floatField(humidity0025(3), func(d *FormatE1Data) **float64 { return &d.Humidity }),
+	intField(pressure50000(5), func(d *FormatE1Data) **int { return &d.Pressure }),
+	floatField(formatE1PM("pm1_0_ug_m3", "PM1.0", 7), func(d *FormatE1Data) **float64 { return &d.PM10 }),
+	floatField(formatE1PM("pm2_5_ug_m3", "PM2.5", 9), func(d *FormatE1Data) **float64 { return &d.PM25 }),
+	floatField(formatE1PM("pm4_0_ug_m3", "PM4.0", 11), func(d *FormatE1Data) **float64 { return &d.PM40 }),
+	floatField(formatE1PM("pm10_0_ug_m3", "PM10", 13), func(d *FormatE1Data) **float64 { return &d.PM100 }),
+	intField(linear("co2_ppm", "CO2", 15, 2, false, 1, 0, "ppm").withSentinel(0xFFFF), func(d *FormatE1Data) **int { return &d.CO2 }),
+	intField(linear("voc_index", "VOC index", 17, 1, false, 1, 0, "").withLSB(28, formatE1FlagVOCLSB).withSentinel(0x1FF), func(d *FormatE1Data) **int { return &d.VOCIndex }),
+	intField(linear("nox_index", "NOx index", 18, 1, false, 1, 0, "").withLSB(28, formatE1FlagNOXLSB).withSentinel(0x1FF), func(d *FormatE1Data) **int { return &d.NOXIndex }),
+	floatField(linear("luminosity_lux", "luminosity", 19, 3, false, 0.01, 0, "lx").withSentinel(0xFFFFFF), func(d *FormatE1Data) **float64 { return &d.Luminosity }),
+	floatField(formatE1Sound("sound_instant_dba", "instant sound level", 22, formatE1FlagSoundInstantLSB), func(d *FormatE1Data) **float64 { return &d.SoundInstant }),
+	floatField(formatE1Sound("sound_average_dba", "average sound level", 23, formatE1FlagSoundAverageLSB), func(d *FormatE1Data) **float64 { return &d.SoundAverage }),
+	floatField(formatE1Sound("sound_peak_dba", "peak sound level", 24, formatE1FlagSoundPeakLSB), func(d *FormatE1Data) **float64 { return &d.SoundPeak }),
+	intField(linear("measurement_sequence", "measurement sequence", 25, 3, false, 1, 0, "").withSentinel(0xFFFFFF), func(d *FormatE1Data) **uint32 { return &d.MeasurementSequence }),
+	flagsField(flags(28, formatE1LSBFlags), func(d *FormatE1Data) *uint8 { return &d.Flags }),
+	{Field: raw("reserved", "reserved", 29, 5)},
+	macField(macAddress("mac_address", "MAC address", 34, 6), func(d *FormatE1Data) **common.MACAddress { return &d.MACAddress }),
+}}
+
+// formatE1PM returns the layout of a particulate matter value in 0.1 µg/m³ increments.
+func formatE1PM(name, label string, offset int) Field {
+	return linear(name, label, offset, 2, false, 0.1, 0, "µg/m³").withSentinel(0xFFFF)
+}
+
+// formatE1Sound returns the layout of a 9-bit sound level in 0.2 dBA
+// increments offset by 18 dBA, with its LSB at lsbMask in the flags byte.
+func formatE1Sound(name, label string, offset int, lsbMask uint8) Field {
+	return linear(name, label, offset, 1, false, 0.2, 18, "dBA").withLSB(28, lsbMask).withSentinel(0x1FF)
+}
+
+// DecodeFormatE1 decodes Ruuvi Extended Data Format E1 from raw bytes.
+// The input must be exactly 40 bytes: 1 byte format ID + 39 bytes data.
+// Returns an error if the data is invalid or not Format E1.
+func DecodeFormatE1(data []byte) (*FormatE1Data, error) {
+	if err := formatE1Codec.validate(data); err != nil {
+		return nil, err
+	}
+
+	result := &FormatE1Data{}
+	formatE1Codec.decode(data, result)
+
+	return result, nil
+}
@@ -0,0 +1,127 @@
+package tag
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/marcgeld/ruuvi/common"
+)
+
+// TestDecodeFormatE1_ValidData tests decoding a fully populated Format E1 payload.
+func TestDecodeFormatE1_ValidData(t *testing.T) {
+	raw, err := hex.DecodeString("E1170C5668C79E000B0070009100A300C905010013DE6D647A0000CD00FFFFFFFFFFCBB8334C884F")
+	if err != nil {
+		t.Fatalf("Failed to decode hex: %v", err)
+	}
+
+	data, err := DecodeFormatE1(raw)
+	if err != nil {
+		t.Fatalf("DecodeFormatE1 failed: %v", err)
+	}
+
+	floats := []struct {
+		name string
+		got  *float64
+		want float64
+	}{
+		{"Temperature", data.Temperature, 29.5},
+		{"Humidity", data.Humidity, 55.3},
+		{"PM10", data.PM10, 1.1},
+		{"PM25", data.PM25, 11.2},
+		{"PM40", data.PM40, 14.5},
+		{"PM100", data.PM100, 16.3},
+		{"Luminosity", data.Luminosity, 50.86},
+		{"SoundInstant", data.SoundInstant, 61.6},
+		{"SoundAverage", data.SoundAverage, 58.0},
+		{"SoundPeak", data.SoundPeak, 66.8},
+	}
+	for _, f := range floats {
+		if f.got == nil {
+			t.Errorf("%s should not be nil", f.name)
+		} else if !floatEquals(*f.got, f.want, 0.001) {
+			t.Errorf("%s = %v, want %v", f.name, *f.got, f.want)
+		}
+	}
+
+	if data.Pressure == nil || *data.Pressure != 101102 {
+		t.Errorf("Pressure = %v, want 101102", data.Pressure)
+	}
+	if data.CO2 == nil || *data.CO2 != 201 {
+		t.Errorf("CO2 = %v, want 201", data.CO2)
+	}
+	if data.VOCIndex == nil || *data.VOCIndex != 10 {
+		t.Errorf("VOCIndex = %v, want 10", data.VOCIndex)
+	}
+	if data.NOXIndex == nil || *data.NOXIndex != 2 {
+		t.Errorf("NOXIndex = %v, want 2", data.NOXIndex)
+	}
+	if data.MeasurementSequence == nil || *data.MeasurementSequence != 205 {
+		t.Errorf("MeasurementSequence = %v, want 205", data.MeasurementSequence)
+	}
+	if data.CalibrationInProgress() {
+		t.Error("CalibrationInProgress() = true, want false")
+	}
+
+	wantMAC := common.MACAddress{0xCB, 0xB8, 0x33, 0x4C, 0x88, 0x4F}
+	if data.MACAddress == nil || *data.MACAddress != wantMAC {
+		t.Errorf("MACAddress = %v, want %v", data.MACAddress, wantMAC)
+	}
+}
+
+// TestDecodeFormatE1_InvalidValues tests that sentinel values decode to nil.
+func TestDecodeFormatE1_InvalidValues(t *testing.T) {
+	raw, err := hex.DecodeString("E18000FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF8FFFFFFFFFFFFFFFFFFFFFF")
+	if err != nil {
+		t.Fatalf("Failed to decode hex: %v", err)
+	}
+
+	data, err := DecodeFormatE1(raw)
+	if err != nil {
+		t.Fatalf("DecodeFormatE1 failed: %v", err)
+	}
+
+	if data.Temperature != nil || data.Humidity != nil || data.Pressure != nil {
+		t.Error("environmental fields should be nil for invalid values")
+	}
+	if data.PM10 != nil || data.PM25 != nil || data.PM40 != nil || data.PM100 != nil {
+		t.Error("particulate matter fields should be nil for invalid values")
+	}
+	if data.CO2 != nil || data.VOCIndex != nil || data.NOXIndex != nil {
+		t.Error("gas fields should be nil for invalid values")
+	}
+	if data.Luminosity != nil {
+		t.Error("Luminosity should be nil for invalid value")
+	}
+	if data.SoundInstant != nil || data.SoundAverage != nil || data.SoundPeak != nil {
+		t.Error("sound fields should be nil for invalid values")
+	}
+	if data.MeasurementSequence != nil {
+		t.Error("MeasurementSequence should be nil for invalid value")
+	}
+	if data.MACAddress != nil {
+		t.Error("MACAddress should be nil for invalid value")
+	}
+	if data.Flags != 0 {
+		t.Errorf("Flags = 0x%02X, want LSB bits stripped", data.Flags)
+	}
+}
+
+// TestDecodeFormatE1_Errors tests error conditions.
+func TestDecodeFormatE1_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "too short", data: []byte{0xE1, 0x17, 0x0C}},
+		{name: "too long", data: make([]byte, 41)},
+		{name: "wrong format", data: make([]byte, 40)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeFormatE1(tt.data); err == nil {
+				t.Error("DecodeFormatE1() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package tag
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+)
+
+// jsonSchema is the JSON Schema describing the JSON encoding of DecodedData.
+//
+//go:embed decoded_data.schema.json
+var jsonSchema []byte
+
+// JSONSchema returns the JSON Schema (draft 2020-12) describing the JSON
+// encoding of DecodedData, for validating payloads in other services.
+func JSONSchema() []byte {
+	return append([]byte(nil), jsonSchema...)
+}
+
+// decodedDataJSON is the JSON representation of DecodedData.
+type decodedDataJSON struct {
+	Format DataFormat `json:"format"`
+	Data   any        `json:"data,omitempty"`
+}
+
+// MarshalJSON encodes the data as an object holding the numeric format byte
+// and the populated format-specific data, for example:
+//
+//	{"format": 5, "data": {"temperature_c": 24.3, ..., "mac_address": "CB:B8:33:4C:88:4F"}}
+//
+// Keys are snake_case and carry the unit of the value. Unavailable values are
+// null. See JSONSchema for the full schema.
+func (d DecodedData) MarshalJSON() ([]byte, error) {
+	return json.Marshal(decodedDataJSON{Format: d.Format, Data: d.payload()})
+}
+
+// UnmarshalJSON decodes data encoded by MarshalJSON. The data object is
+// decoded into the format-specific field matching the format byte; data of
+// unregistered or custom formats is kept in Custom as a json.RawMessage.
+// Unknown keys are an error rather than being dropped silently.
+func (d *DecodedData) UnmarshalJSON(b []byte) error {
+	var aux struct {
+		Format *DataFormat     `json:"format"`
+		Data   json.RawMessage `json:"data"`
+	}
+	if err := unmarshalStrict(b, &aux); err != nil {
+		return err
+	}
+
+	if aux.Format == nil {
+		return fmt.Errorf("decoded data: missing format")
+	}
+
+	*d = DecodedData{Format: *aux.Format}
+	if len(aux.Data) == 0 || string(aux.Data) == "null" {
+		return nil
+	}
+
+	var target any
+	switch d.Format {
+	case Format2:
+		d.Format2 = &Format2Data{}
+		target = d.Format2
+	case Format3:
+		d.Format3 = &Format3Data{}
+		target = d.Format3
+	case Format4:
+		d.Format4 = &Format4Data{}
+		target = d.Format4
+	case Format5:
+		d.Format5 = &Format5Data{}
+		target = d.Format5
+	case Format6:
+		d.Format6 = &Format6Data{}
+		target = d.Format6
+	case Format8:
+		d.Format8 = &Format8Data{}
+		target = d.Format8
+	case FormatC5:
+		d.FormatC5 = &FormatC5Data{}
+		target = d.FormatC5
+	case FormatE1:
+		d.FormatE1 = &FormatE1Data{}
+		target = d.FormatE1
+	default:
+		d.Custom = aux.Data
+		return nil
+	}
+
+	if err := unmarshalStrict(aux.Data, target); err != nil {
+		return fmt.Errorf("decoded data: format %s: %w", d.Format, err)
+	}
+
+	return nil
+}
+
+// unmarshalStrict is json.Unmarshal rejecting keys that do not map to a
+// field of v.
+func unmarshalStrict(b []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// payload returns the populated format-specific field, or nil if none is.
+func (d *DecodedData) payload() any {
+	switch {
+	case d.Format2 != nil:
+		return d.Format2
+	case d.Format3 != nil:
+		return d.Format3
+	case d.Format4 != nil:
+		return d.Format4
+	case d.Format5 != nil:
+		return d.Format5
+	case d.Format6 != nil:
+		return d.Format6
+	case d.Format8 != nil:
+		return d.Format8
+	case d.FormatC5 != nil:
+		return d.FormatC5
+	case d.FormatE1 != nil:
+		return d.FormatE1
+	case d.Custom != nil:
+		return d.Custom
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,213 @@
+package tag
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// jsonTestVectors holds one fully populated payload per built-in format.
+var jsonTestVectors = map[string]string{
+	"format2":   "023C1800C15C",
+	"format3":   "03291A1ECE1EFC18F94202CA0B53",
+	"format4":   "04480000C4384C",
+	"format5":   "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F",
+	"format6":   "06170C5668C79E007000C90501D9FFCD004C884F",
+	"format8":   format8TestVector,
+	"format_c5": "C512FC5394C37CAC364200CDCBB8334C884F",
+	"format_e1": "E1170C5668C79E000B0070009100A300C905010013DE6D647A0000CD00FFFFFFFFFFCBB8334C884F",
+}
+
+// useFormat8TestKey installs the Format 8 test key in DefaultKeyStore for the duration of the test.
+func useFormat8TestKey(t *testing.T) {
+	t.Helper()
+	keys := NewMemoryKeyStore()
+	if err := keys.Set(format8TestMAC, mustDecodeHex(t, format8TestKey)); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	orig := DefaultKeyStore
+	DefaultKeyStore = keys
+	t.Cleanup(func() { DefaultKeyStore = orig })
+}
+
+func TestDecodedData_MarshalJSON(t *testing.T) {
+	decoded, err := Decode(mustDecodeHex(t, jsonTestVectors["format5"]))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	b, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+
+	want := `{"format":5,"data":{"temperature_c":24.3,"humidity_percent":53.49,"pressure_pa":100044,` +
+		`"acceleration_x_g":0.004,"acceleration_y_g":-0.004,"acceleration_z_g":1.036,` +
+		`"battery_voltage_mv":2977,"tx_power_dbm":4,"movement_counter":66,"measurement_sequence":205,` +
+		`"mac_address":"CB:B8:33:4C:88:4F"}}`
+	if string(b) != want {
+		t.Errorf("json.Marshal =\n%s\nwant\n%s", b, want)
+	}
+}
+
+func TestDecodedData_MarshalJSON_Unavailable(t *testing.T) {
+	decoded, err := Decode(mustDecodeHex(t, "058000FFFFFFFF800080008000FFFFFFFFFFFFFFFFFFFFFF"))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	b, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(b), `"temperature_c":null`) || !strings.Contains(string(b), `"mac_address":null`) {
+		t.Errorf("json.Marshal = %s, want null for unavailable values", b)
+	}
+
+	b, err = json.Marshal(&DecodedData{Format: Format5})
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	if string(b) != `{"format":5}` {
+		t.Errorf("json.Marshal = %s, want {\"format\":5}", b)
+	}
+}
+
+func TestDecodedData_JSONRoundTrip(t *testing.T) {
+	useFormat8TestKey(t)
+
+	for name, vector := range jsonTestVectors {
+		t.Run(name, func(t *testing.T) {
+			decoded, err := Decode(mustDecodeHex(t, vector))
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+
+			b, err := json.Marshal(decoded)
+			if err != nil {
+				t.Fatalf("json.Marshal failed: %v", err)
+			}
+
+			var got DecodedData
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatalf("json.Unmarshal failed: %v", err)
+			}
+			if !reflect.DeepEqual(&got, decoded) {
+				t.Errorf("round trip = %+v, want %+v", got, *decoded)
+			}
+		})
+	}
+}
+
+func TestDecodedData_UnmarshalJSON(t *testing.T) {
+	var custom DecodedData
+	if err := json.Unmarshal([]byte(`{"format":240,"data":{"counter":7}}`), &custom); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	if raw, ok := custom.Custom.(json.RawMessage); !ok || string(raw) != `{"counter":7}` {
+		t.Errorf("Custom = %#v, want raw data", custom.Custom)
+	}
+
+	for _, input := range []string{
+		`{}`,
+		`{"format":5,"data":{"mac_address":"nope"}}`,
+		`{"format":6,"data":{"mac_suffix":"4C:88"}}`,
+		`{"format":5,"data":{"Temperature":24.3,"Humidity":53.49}}`,
+		`{"format":6,"data":{"temperature_c":24.3,"voc":10}}`,
+		`{"format":5,"data":{},"extra":1}`,
+	} {
+		var d DecodedData
+		if err := json.Unmarshal([]byte(input), &d); err == nil {
+			t.Errorf("json.Unmarshal(%s) expected error, got nil", input)
+		}
+	}
+}
+
+func TestJSONSchema_MatchesEncoding(t *testing.T) {
+	useFormat8TestKey(t)
+
+	var schema struct {
+		Defs map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(JSONSchema(), &schema); err != nil {
+		t.Fatalf("JSONSchema is not valid JSON: %v", err)
+	}
+
+	for name, vector := range jsonTestVectors {
+		decoded, err := Decode(mustDecodeHex(t, vector))
+		if err != nil {
+			t.Fatalf("%s: Decode failed: %v", name, err)
+		}
+
+		b, err := json.Marshal(decoded.payload())
+		if err != nil {
+			t.Fatalf("%s: json.Marshal failed: %v", name, err)
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(b, &fields); err != nil {
+			t.Fatalf("%s: json.Unmarshal failed: %v", name, err)
+		}
+
+		def, ok := schema.Defs[name]
+		if !ok {
+			t.Errorf("schema has no definition for %s", name)
+			continue
+		}
+		got := slices.Sorted(maps.Keys(fields))
+		want := slices.Sorted(maps.Keys(def.Properties))
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: encoded keys %v, schema properties %v", name, got, want)
+		}
+	}
+}
+
+func TestEncode_RoundTrip(t *testing.T) {
+	useFormat8TestKey(t)
+
+	for name, vector := range jsonTestVectors {
+		if name == "format_e1" {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			raw := mustDecodeHex(t, vector)
+			decoded, err := Decode(raw)
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+
+			encoded, err := Encode(decoded)
+			if err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+			if !bytesEqual(encoded, raw) {
+				t.Errorf("Encode() = %X, want %X", encoded, raw)
+			}
+		})
+	}
+}
+
+func TestEncode_Unsupported(t *testing.T) {
+	decoded, err := Decode(mustDecodeHex(t, jsonTestVectors["format_e1"]))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	if _, err := Encode(decoded); !errors.Is(err, ErrEncodingUnsupported) {
+		t.Errorf("Encode(E1) error = %v, want ErrEncodingUnsupported", err)
+	}
+	if _, err := Encode(&DecodedData{Format: 0xF0, Custom: 1}); !errors.Is(err, ErrEncodingUnsupported) {
+		t.Errorf("Encode(custom) error = %v, want ErrEncodingUnsupported", err)
+	}
+	if _, err := Encode(nil); !errors.Is(err, ErrNilData) {
+		t.Errorf("Encode(nil) error = %v, want ErrNilData", err)
+	}
+	if _, err := Encode(&DecodedData{Format: Format8, Format8: &Format8Data{}}); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Encode(format 8 without MAC) error = %v, want ErrKeyNotFound", err)
+	}
+}
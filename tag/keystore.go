@@ -0,0 +1,141 @@
+package tag
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/marcgeld/ruuvi/common"
+)
+
+// KeyStore looks up Data Format 8 decryption keys by tag MAC address.
+type KeyStore interface {
+	// Key returns the AES-128 key for the tag with the given MAC address,
+	// or false if no key is known.
+	Key(mac common.MACAddress) ([]byte, bool)
+}
+
+// DefaultKeyStore is consulted by Decode to find the decryption key for
+// Data Format 8 payloads. It is nil by default, in which case Decode returns
+// a *KeyNotFoundError for every Format 8 payload. Set it during program
+// initialization, before any concurrent calls to Decode.
+var DefaultKeyStore KeyStore
+
+// MemoryKeyStore is an in-memory KeyStore. It is safe for concurrent use.
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys map[common.MACAddress][]byte
+}
+
+// NewMemoryKeyStore returns an empty MemoryKeyStore.
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{keys: make(map[common.MACAddress][]byte)}
+}
+
+// Set stores the AES-128 key for the tag with the given MAC address,
+// replacing any existing key. The key must be exactly 16 bytes.
+func (s *MemoryKeyStore) Set(mac common.MACAddress, key []byte) error {
+	if len(key) != Format8KeySize {
+		return fmt.Errorf("%w: must be %d bytes, got %d", ErrInvalidKey, Format8KeySize, len(key))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[mac] = bytes.Clone(key)
+	return nil
+}
+
+// Delete removes the key for the tag with the given MAC address.
+func (s *MemoryKeyStore) Delete(mac common.MACAddress) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, mac)
+}
+
+// Key implements KeyStore. The returned key is a copy the caller may modify.
+func (s *MemoryKeyStore) Key(mac common.MACAddress) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[mac]
+	return bytes.Clone(key), ok
+}
+
+// FileKeyStore is a KeyStore backed by a text file. It is safe for concurrent use.
+//
+// Each non-empty line of the file holds a MAC address and a hex-encoded
+// 16-byte key separated by whitespace. Lines starting with '#' are comments:
+//
+//	# kitchen
+//	CB:B8:33:4C:88:4F 00112233445566778899AABBCCDDEEFF
+type FileKeyStore struct {
+	path string
+
+	mu    sync.RWMutex
+	store *MemoryKeyStore
+}
+
+// NewFileKeyStore loads keys from the file at path.
+func NewFileKeyStore(path string) (*FileKeyStore, error) {
+	s := &FileKeyStore{path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload re-reads the key file. On error the previously loaded keys are kept.
+func (s *FileKeyStore) Reload() error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return fmt.Errorf("open key file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	store := NewMemoryKeyStore()
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return fmt.Errorf("%s:%d: want MAC address and key, got %d fields", s.path, lineNo, len(fields))
+		}
+
+		mac, err := common.ParseMACAddress(fields[0])
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", s.path, lineNo, err)
+		}
+
+		key, err := hex.DecodeString(fields[1])
+		if err != nil {
+			return fmt.Errorf("%s:%d: invalid key: %w", s.path, lineNo, err)
+		}
+
+		if err := store.Set(mac, key); err != nil {
+			return fmt.Errorf("%s:%d: %w", s.path, lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read key file: %w", err)
+	}
+
+	s.mu.Lock()
+	s.store = store
+	s.mu.Unlock()
+	return nil
+}
+
+// Key implements KeyStore.
+func (s *FileKeyStore) Key(mac common.MACAddress) ([]byte, bool) {
+	s.mu.RLock()
+	store := s.store
+	s.mu.RUnlock()
+	return store.Key(mac)
+}
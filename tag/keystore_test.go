@@ -0,0 +1,95 @@
+package tag
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcgeld/ruuvi/common"
+)
+
+func TestMemoryKeyStore(t *testing.T) {
+	keys := NewMemoryKeyStore()
+	mac := common.MACAddress{0, 1, 2, 3, 4, 5}
+
+	if _, ok := keys.Key(mac); ok {
+		t.Fatal("Key() on empty store returned ok")
+	}
+
+	if err := keys.Set(mac, make([]byte, 15)); err == nil {
+		t.Fatal("Set() with 15-byte key returned nil error")
+	}
+
+	key := []byte("0123456789abcdef")
+	if err := keys.Set(mac, key); err != nil {
+		t.Fatalf("Set() error: %v", err)
+	}
+	key[0] = 'X' // the store must keep its own copy
+
+	got, ok := keys.Key(mac)
+	if !ok || string(got) != "0123456789abcdef" {
+		t.Fatalf("Key() = %q, %v; want stored key", got, ok)
+	}
+	got[0] = 'X' // callers get a copy as well
+	if got, _ := keys.Key(mac); string(got) != "0123456789abcdef" {
+		t.Fatalf("Key() after modifying the returned key = %q, want stored key", got)
+	}
+
+	keys.Delete(mac)
+	if _, ok := keys.Key(mac); ok {
+		t.Fatal("Key() after Delete returned ok")
+	}
+}
+
+func TestFileKeyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.txt")
+	content := "# test keys\n\nCB:B8:33:4C:88:4F 00112233445566778899AABBCCDDEEFF\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	keys, err := NewFileKeyStore(path)
+	if err != nil {
+		t.Fatalf("NewFileKeyStore() error: %v", err)
+	}
+
+	if _, ok := keys.Key(format8TestMAC); !ok {
+		t.Fatal("Key() for listed MAC returned !ok")
+	}
+	if _, ok := keys.Key(common.MACAddress{}); ok {
+		t.Fatal("Key() for unlisted MAC returned ok")
+	}
+
+	// A broken file must not replace the previously loaded keys.
+	if err := os.WriteFile(path, []byte("CB:B8:33:4C:88:4F nothex\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := keys.Reload(); err == nil {
+		t.Fatal("Reload() of invalid file returned nil error")
+	}
+	if _, ok := keys.Key(format8TestMAC); !ok {
+		t.Fatal("Key() after failed Reload lost existing key")
+	}
+}
+
+func TestNewFileKeyStore_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := NewFileKeyStore(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Fatal("NewFileKeyStore() of missing file returned nil error")
+	}
+
+	for name, content := range map[string]string{
+		"fields": "CB:B8:33:4C:88:4F\n",
+		"mac":    "CB:B8:33 00112233445566778899AABBCCDDEEFF\n",
+		"length": "CB:B8:33:4C:88:4F 0011\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if _, err := NewFileKeyStore(path); err == nil {
+			t.Errorf("NewFileKeyStore(%s) returned nil error", name)
+		}
+	}
+}
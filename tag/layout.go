@@ -0,0 +1,215 @@
+package tag
+
+import "fmt"
+
+// FieldKind tells how the raw bits of a field map to its physical value.
+type FieldKind uint8
+
+const (
+	FieldLinear        FieldKind = iota // Value = raw*Scale + ValueOffset
+	FieldSignMagnitude                  // Sign bit and whole units, then an optional hundredths byte
+	FieldLogarithmic                    // Value = exp(raw*Scale) - 1
+	FieldMAC                            // MAC address or suffix, all 0xFF when not available
+	FieldFlags                          // Status bits, without the bits listed in LSBMask
+	FieldRaw                            // Not decoded: format byte, reserved bytes, CRC, ciphertext
+)
+
+// String returns the name of the kind, e.g. "linear".
+func (k FieldKind) String() string {
+	switch k {
+	case FieldLinear:
+		return "linear"
+	case FieldSignMagnitude:
+		return "sign-magnitude"
+	case FieldLogarithmic:
+		return "logarithmic"
+	case FieldMAC:
+		return "MAC"
+	case FieldFlags:
+		return "flags"
+	case FieldRaw:
+		return "raw"
+	default:
+		return fmt.Sprintf("FieldKind(%d)", uint8(k))
+	}
+}
+
+// Field describes where a field is stored in a payload and how its raw value
+// maps to a physical value. The decoders and encoders of the built-in formats
+// are driven by these descriptions, see Fields.
+type Field struct {
+	Name  string    // JSON key, e.g. "temperature_c"
+	Label string    // Human-readable name used in errors, e.g. "temperature"
+	Kind  FieldKind // How the raw value maps to the physical value
+	Unit  string    // Unit of the physical value, empty if unitless
+
+	Offset int  // Offset of the first byte
+	Size   int  // Number of bytes, read big-endian
+	Shift  uint // Right shift applied to the bytes read
+	Bits   uint // Width of the field after shifting, 0 for Size*8
+	Signed bool // Two's complement
+
+	// LSBOffset and LSBMask locate a least significant bit stored apart from
+	// the other bits of the field, in the flags byte of Formats 6 and E1.
+	// For FieldFlags, LSBMask holds the flag bits that belong to other fields.
+	LSBOffset int
+	LSBMask   uint8
+
+	Scale       float64 // See FieldLinear and FieldLogarithmic
+	ValueOffset float64 // See FieldLinear
+
+	HasSentinel bool
+	Sentinel    int64 // Raw "not available" value
+
+	Encrypted bool // Offset is in the decrypted Data Format 8 block
+}
+
+// Fields returns the fields of a built-in data format in payload order,
+// starting with the format byte. The fields of the encrypted Data Format 8
+// block follow the transmitted fields and have Encrypted set. Returns nil for
+// formats without a built-in layout, including formats added with
+// RegisterFormat.
+//
+// The returned slice is a copy and may be modified.
+func Fields(format DataFormat) []Field {
+	layout, ok := layouts[format]
+	if !ok {
+		return nil
+	}
+
+	fields := append([]Field(nil), layout.fields...)
+	if format == Format8 {
+		fields = append(fields, format8PlainLayout.fields...)
+	}
+	return fields
+}
+
+// formatLayout lists the fields of a data format in payload order.
+type formatLayout struct {
+	size   int // Payload length in bytes, including the format byte
+	fields []Field
+}
+
+// linear returns the layout of a field with value raw*scale + bias.
+func linear(name, label string, offset, size int, signed bool, scale, bias float64, unit string) Field {
+	return Field{
+		Name: name, Label: label, Offset: offset, Size: size, Signed: signed,
+		Scale: scale, ValueOffset: bias, Unit: unit,
+	}
+}
+
+// raw returns the layout of bytes that are not decoded.
+func raw(name, label string, offset, size int) Field {
+	return Field{Name: name, Label: label, Offset: offset, Size: size, Kind: FieldRaw}
+}
+
+// macAddress returns the layout of a MAC address or suffix of size bytes.
+func macAddress(name, label string, offset, size int) Field {
+	return Field{Name: name, Label: label, Offset: offset, Size: size, Kind: FieldMAC}
+}
+
+// flags returns the layout of a flags byte whose bits in lsbMask belong to
+// other fields.
+func flags(offset int, lsbMask uint8) Field {
+	return Field{Name: "flags", Label: "flags", Offset: offset, Size: 1, LSBMask: lsbMask, Kind: FieldFlags}
+}
+
+// withSentinel sets the raw "not available" value of a field.
+func (f Field) withSentinel(s int64) Field {
+	f.HasSentinel = true
+	f.Sentinel = s
+	return f
+}
+
+// withBits narrows a field to bits bits, starting shift bits from the right.
+func (f Field) withBits(shift, bits uint) Field {
+	f.Shift = shift
+	f.Bits = bits
+	return f
+}
+
+// withLSB appends a least significant bit stored at mask in byte offset.
+func (f Field) withLSB(offset int, mask uint8) Field {
+	f.LSBOffset = offset
+	f.LSBMask = mask
+	return f
+}
+
+// formatByte is the first byte of every payload.
+var formatByte = raw("format", "format", 0, 1)
+
+// Shared field layouts of the RAWv2 family (Formats 5, 8 and C5) and the air
+// quality formats (6 and E1), at the given offset.
+func temperature005(offset int) Field {
+	return linear("temperature_c", "temperature", offset, 2, true, 0.005, 0, "°C").withSentinel(-0x8000)
+}
+
+func humidity0025(offset int) Field {
+	return linear("humidity_percent", "humidity", offset, 2, false, 0.0025, 0, "%").withSentinel(0xFFFF)
+}
+
+func pressure50000(offset int) Field {
+	return linear("pressure_pa", "pressure", offset, 2, false, 1, 50000, "Pa").withSentinel(0xFFFF)
+}
+
+func rawv2Acceleration(name, label string, offset int) Field {
+	return linear(name, label, offset, 2, true, 0.001, 0, "g").withSentinel(-0x8000)
+}
+
+func rawv2BatteryVoltage(offset int) Field {
+	return linear("battery_voltage_mv", "battery voltage", offset, 2, false, 1, 1600, "mV").withBits(5, 11).withSentinel(0x7FF)
+}
+
+func rawv2TxPower(offset int) Field {
+	return linear("tx_power_dbm", "TX power", offset, 2, false, 2, -40, "dBm").withBits(0, 5).withSentinel(0x1F)
+}
+
+func rawv2MovementCounter(offset int) Field {
+	return linear("movement_counter", "movement counter", offset, 1, false, 1, 0, "").withSentinel(0xFF)
+}
+
+func rawv2Sequence(offset int) Field {
+	return linear("measurement_sequence", "measurement sequence", offset, 2, false, 1, 0, "").withSentinel(0xFFFF)
+}
+
+// Field layouts of the URL formats and RAWv1, which use 0 as sentinel.
+func humidity05(offset int) Field {
+	return linear("humidity_percent", "humidity", offset, 1, false, 0.5, 0, "%").withSentinel(0)
+}
+
+func pressureZero(offset int) Field {
+	return linear("pressure_pa", "pressure", offset, 2, false, 1, 50000, "Pa").withSentinel(0)
+}
+
+// signMagnitudeTemperature is the temperature of Formats 2, 3 and 4: whole
+// degrees, followed in Format 3 by a hundredths byte. Formats 2 and 4 always
+// send a zero fraction byte, which is not part of the field.
+func signMagnitudeTemperature(size int) Field {
+	return Field{
+		Name: "temperature_c", Label: "temperature", Offset: 2, Size: size, Unit: "°C",
+		HasSentinel: true, Kind: FieldSignMagnitude,
+	}
+}
+
+// layouts holds the layout of every built-in format. Format 8 lists the
+// fields of the payload as transmitted; see format8PlainLayout for the
+// encrypted block.
+var layouts = map[DataFormat]formatLayout{
+	Format2:  format2Codec.layout(),
+	Format3:  format3Codec.layout(),
+	Format4:  format4Codec.layout(),
+	Format5:  format5Codec.layout(),
+	Format6:  format6Codec.layout(),
+	Format8:  format8Codec.layout(),
+	FormatC5: formatC5Codec.layout(),
+	FormatE1: formatE1Codec.layout(),
+}
+
+// format8PlainLayout is the layout of the decrypted Data Format 8 block.
+var format8PlainLayout = func() formatLayout {
+	layout := format8PlainCodec.layout()
+	for i := range layout.fields {
+		layout.fields[i].Encrypted = true
+	}
+	return layout
+}()
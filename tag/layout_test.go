@@ -0,0 +1,153 @@
+package tag
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestFields(t *testing.T) {
+	fields := Fields(Format5)
+	if len(fields) != 12 || fields[0].Name != "format" || fields[0].Kind != FieldRaw {
+		t.Fatalf("unexpected Format 5 fields: %+v", fields)
+	}
+
+	temp := fields[1]
+	if temp.Name != "temperature_c" || temp.Unit != "°C" || temp.Offset != 1 || temp.Size != 2 ||
+		!temp.Signed || temp.Scale != 0.005 || !temp.HasSentinel || temp.Sentinel != -0x8000 {
+		t.Errorf("temperature_c = %+v", temp)
+	}
+
+	// The result is a copy
+	fields[1].Unit = "K"
+	if Fields(Format5)[1].Unit != "°C" {
+		t.Error("Fields returned the shared layout")
+	}
+
+	if Fields(0x42) != nil {
+		t.Error("Fields(0x42) should be nil")
+	}
+}
+
+func TestFields_Format8(t *testing.T) {
+	var encrypted []string
+	for _, f := range Fields(Format8) {
+		if f.Encrypted {
+			encrypted = append(encrypted, f.Name)
+		}
+	}
+	if len(encrypted) != 8 || encrypted[0] != "temperature_c" || encrypted[7] != "reserved" {
+		t.Errorf("encrypted fields = %v", encrypted)
+	}
+}
+
+func TestFields_MatchJSONKeys(t *testing.T) {
+	useFormat8TestKey(t)
+
+	for name, vector := range jsonTestVectors {
+		t.Run(name, func(t *testing.T) {
+			decoded, err := Decode(mustDecodeHex(t, vector))
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			b, err := json.Marshal(decoded.payload())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var keys map[string]any
+			if err := json.Unmarshal(b, &keys); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, f := range Fields(decoded.Format) {
+				if _, ok := keys[f.Name]; !ok && f.Kind != FieldRaw {
+					t.Errorf("field %s is not a JSON key", f.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestDecode_DecimalScaling(t *testing.T) {
+	d, err := DecodeFormat6(mustDecodeHex(t, jsonTestVectors["format6"]))
+	if err != nil {
+		t.Fatalf("DecodeFormat6 failed: %v", err)
+	}
+
+	// Values are the float64 closest to the decimal reading, not raw*0.0025
+	if *d.Humidity != 55.3 || *d.PM25 != 11.2 {
+		t.Errorf("Humidity = %v, PM25 = %v, want 55.3 and 11.2", *d.Humidity, *d.PM25)
+	}
+}
+
+func TestEncode_RangeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		encode func() ([]byte, error)
+		field  string
+	}{
+		{"format 2 temperature", func() ([]byte, error) { return EncodeFormat2(&Format2Data{Temperature: float64Ptr(128)}) }, "temperature"},
+		{"format 3 humidity", func() ([]byte, error) { return EncodeFormat3(&Format3Data{Humidity: float64Ptr(128)}) }, "humidity"},
+		{"format 3 temperature rounding up", func() ([]byte, error) { return EncodeFormat3(&Format3Data{Temperature: float64Ptr(127.999)}) }, "temperature"},
+		{"format 3 acceleration", func() ([]byte, error) { return EncodeFormat3(&Format3Data{AccelerationZ: float64Ptr(-33)}) }, "acceleration Z"},
+		{"format 4 pressure", func() ([]byte, error) { return EncodeFormat4(&Format4Data{Pressure: intPtr(40000)}) }, "pressure"},
+		{"format 6 CO2", func() ([]byte, error) { return EncodeFormat6(&Format6Data{CO2: intPtr(70000)}) }, "CO2"},
+		{"format 6 VOC index", func() ([]byte, error) { return EncodeFormat6(&Format6Data{VOCIndex: intPtr(511)}) }, "VOC index"},
+		{"format C5 TX power", func() ([]byte, error) { return EncodeFormatC5(&FormatC5Data{TxPower: intPtr(-42)}) }, "TX power"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.encode()
+			var rangeErr *RangeError
+			if !errors.As(err, &rangeErr) {
+				t.Fatalf("error = %v, want *RangeError", err)
+			}
+			if rangeErr.Field != tt.field {
+				t.Errorf("Field = %q, want %q", rangeErr.Field, tt.field)
+			}
+		})
+	}
+}
+
+func TestEncode_SignMagnitudeCarry(t *testing.T) {
+	tests := []struct {
+		temperature float64
+		want        [2]byte
+	}{
+		{21.999, [2]byte{22, 0}},
+		{-21.999, [2]byte{0x80 | 22, 0}},
+		{21.994, [2]byte{21, 99}},
+		{127.994, [2]byte{127, 99}},
+	}
+
+	for _, tt := range tests {
+		encoded, err := EncodeFormat3(&Format3Data{Temperature: &tt.temperature})
+		if err != nil {
+			t.Fatalf("EncodeFormat3(%v) failed: %v", tt.temperature, err)
+		}
+		if got := [2]byte{encoded[2], encoded[3]}; got != tt.want {
+			t.Errorf("EncodeFormat3(%v) temperature bytes = %X, want %X", tt.temperature, got, tt.want)
+		}
+	}
+
+	encoded, err := format3Codec.encode(&Format3Data{Temperature: float64Ptr(127.999)}, EncodeOptions{Clamp: true})
+	if err != nil {
+		t.Fatalf("encode with clamping failed: %v", err)
+	}
+	if encoded[2] != 127 || encoded[3] != 99 {
+		t.Errorf("clamped temperature bytes = %X, want 7F63", encoded[2:4])
+	}
+}
+
+func TestEncode_SharedFlagsByte(t *testing.T) {
+	// The VOC and NOx LSBs and the calibration flag share byte 16
+	voc, nox := 3, 2
+	encoded, err := EncodeFormat6(&Format6Data{VOCIndex: &voc, NOXIndex: &nox, Flags: Format6FlagCalibrationInProgress | format6FlagNOXLSB})
+	if err != nil {
+		t.Fatalf("EncodeFormat6 failed: %v", err)
+	}
+	if encoded[11] != 1 || encoded[12] != 1 || encoded[16] != Format6FlagCalibrationInProgress|format6FlagVOCLSB {
+		t.Errorf("encoded = %X", encoded)
+	}
+}
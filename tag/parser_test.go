@@ -363,3 +363,65 @@ func TestParseFormat5_InvalidValues(t *testing.T) {
 		t.Error("macAddress should be nil")
 	}
 }
+
+func TestParseManufacturerData_AllFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		hex      string
+		format   DataFormat
+		temp     float64
+		hasAccel bool
+		hasMAC   bool
+		sequence *uint32
+	}{
+		{name: "Format 3", hex: "03291A1ECE1EFC18F94202CA0B53", format: Format3, temp: 26.3, hasAccel: true},
+		{name: "Format 5", hex: "0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F", format: Format5, temp: 24.3, hasAccel: true, hasMAC: true, sequence: uint32Ptr(205)},
+		{name: "Format 6", hex: "06170C5668C79E007000C90501D9FFCD004C884F", format: Format6, temp: 29.5, sequence: uint32Ptr(205)},
+		{name: "Format C5", hex: "C512FC5394C37CAC364200CDCBB8334C884F", format: FormatC5, temp: 24.3, hasMAC: true, sequence: uint32Ptr(205)},
+		{name: "Format E1", hex: "E1170C5668C79E000B0070009100A300C905010013DE6D647A0000CD00FFFFFFFFFFCBB8334C884F", format: FormatE1, temp: 29.5, hasMAC: true, sequence: uint32Ptr(205)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := hex.DecodeString(tt.hex)
+			if err != nil {
+				t.Fatalf("failed to decode hex: %v", err)
+			}
+
+			m, err := ParseManufacturerData(data)
+			if err != nil {
+				t.Fatalf("ParseManufacturerData failed: %v", err)
+			}
+
+			if m.Format != tt.format {
+				t.Errorf("format = %v, want %v", m.Format, tt.format)
+			}
+			if m.Temperature == nil || math.Abs(*m.Temperature-tt.temp) > 0.001 {
+				t.Errorf("temperature = %v, want %v", m.Temperature, tt.temp)
+			}
+			if (m.AccelerationX != nil) != tt.hasAccel {
+				t.Errorf("accelerationX = %v, want present = %v", m.AccelerationX, tt.hasAccel)
+			}
+			if (m.MACAddress != nil) != tt.hasMAC {
+				t.Errorf("macAddress = %v, want present = %v", m.MACAddress, tt.hasMAC)
+			}
+			if (m.MeasurementSequence == nil) != (tt.sequence == nil) ||
+				(tt.sequence != nil && *m.MeasurementSequence != *tt.sequence) {
+				t.Errorf("measurementSequence = %v, want %v", m.MeasurementSequence, tt.sequence)
+			}
+		})
+	}
+}
+
+func TestDecodedData_Measurement_Empty(t *testing.T) {
+	if m := (&DecodedData{Format: Format5}).Measurement(); m != nil {
+		t.Errorf("Measurement() = %+v, want nil for empty DecodedData", m)
+	}
+
+	var d *DecodedData
+	if m := d.Measurement(); m != nil {
+		t.Errorf("Measurement() = %+v, want nil for nil DecodedData", m)
+	}
+}
+
+func uint32Ptr(v uint32) *uint32 { return &v }
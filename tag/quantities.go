@@ -0,0 +1,56 @@
+package tag
+
+import "github.com/marcgeld/ruuvi/common"
+
+// Quantities holds the values of a measurement as the typed quantities of
+// package common, which convert between units and format themselves. Fields
+// are nil if the value is not available.
+type Quantities struct {
+	Temperature     *common.Temperature
+	Humidity        *common.Humidity
+	Pressure        *common.Pressure
+	Acceleration    *common.Acceleration // Nil unless all three axes are available
+	BatteryVoltage  *common.BatteryVoltage
+	TxPower         *common.TxPower
+	MovementCounter *common.MovementCounter
+}
+
+// Quantities returns the values of m as typed quantities. The measurement
+// sequence is not included, as common.MeasurementSequence holds 16 bits and
+// Format E1 sends 24. Returns all nil fields for a nil m.
+func (m *Measurement) Quantities() Quantities {
+	var q Quantities
+	if m == nil {
+		return q
+	}
+
+	if m.Temperature != nil {
+		q.Temperature = &common.Temperature{Celsius: *m.Temperature}
+	}
+	if m.Humidity != nil {
+		q.Humidity = &common.Humidity{Percent: *m.Humidity}
+	}
+	if m.Pressure != nil {
+		q.Pressure = &common.Pressure{Pascals: int(*m.Pressure)}
+	}
+	if m.AccelerationX != nil && m.AccelerationY != nil && m.AccelerationZ != nil {
+		q.Acceleration = &common.Acceleration{X: *m.AccelerationX, Y: *m.AccelerationY, Z: *m.AccelerationZ}
+	}
+	if m.BatteryVoltage != nil {
+		q.BatteryVoltage = &common.BatteryVoltage{Millivolts: int(*m.BatteryVoltage)}
+	}
+	if m.TxPower != nil {
+		q.TxPower = &common.TxPower{DBm: int(*m.TxPower)}
+	}
+	if m.MovementCounter != nil {
+		q.MovementCounter = &common.MovementCounter{Count: *m.MovementCounter}
+	}
+
+	return q
+}
+
+// Quantities returns the decoded values as typed quantities, see
+// Measurement.Quantities.
+func (d *DecodedData) Quantities() Quantities {
+	return d.Measurement().Quantities()
+}
@@ -0,0 +1,42 @@
+package tag
+
+import "testing"
+
+func TestMeasurement_Quantities(t *testing.T) {
+	decoded, err := Decode(mustDecodeHex(t, jsonTestVectors["format5"]))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	q := decoded.Quantities()
+	if q.Temperature == nil || q.Humidity == nil || q.Pressure == nil || q.Acceleration == nil ||
+		q.BatteryVoltage == nil || q.TxPower == nil || q.MovementCounter == nil {
+		t.Fatalf("Quantities() = %+v, want all fields", q)
+	}
+
+	m := decoded.Measurement()
+	if q.Temperature.Celsius != *m.Temperature || q.Pressure.Pascals != int(*m.Pressure) ||
+		q.Acceleration.Z != *m.AccelerationZ || q.BatteryVoltage.Millivolts != int(*m.BatteryVoltage) {
+		t.Errorf("Quantities() = %+v, want the values of %+v", q, m)
+	}
+	if got := q.Temperature.Fahrenheit(); !floatEquals(got, *m.Temperature*9/5+32, 1e-9) {
+		t.Errorf("Fahrenheit() = %v", got)
+	}
+}
+
+func TestMeasurement_QuantitiesNotAvailable(t *testing.T) {
+	temp := 21.5
+	accX := 0.1
+	q := (&Measurement{Format: Format5, Temperature: &temp, AccelerationX: &accX}).Quantities()
+	if q.Temperature == nil || q.Temperature.Celsius != temp {
+		t.Errorf("Temperature = %v, want %v", q.Temperature, temp)
+	}
+	if q.Humidity != nil || q.Pressure != nil || q.Acceleration != nil || q.TxPower != nil {
+		t.Errorf("Quantities() = %+v, want nil for unavailable values", q)
+	}
+
+	var nilMeasurement *Measurement
+	if q := nilMeasurement.Quantities(); q.Temperature != nil {
+		t.Errorf("Quantities() of nil = %+v", q)
+	}
+}
@@ -0,0 +1,93 @@
+package tag
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Decoder decodes the payload of a single data format.
+//
+// Decode is given the raw data including the format byte. A Decoder that
+// returns one of the FormatNData types of this package, for example a
+// *Format5Data for firmware that reuses the RAWv2 layout under a private format
+// byte, has it stored in the matching field of DecodedData; any other value is
+// stored in DecodedData.Custom. Custom values that implement
+// Measurement() *Measurement are normalized by DecodedData.Measurement.
+type Decoder interface {
+	Decode(data []byte) (any, error)
+}
+
+// DecoderFunc adapts an ordinary function to the Decoder interface.
+type DecoderFunc func(data []byte) (any, error)
+
+// Decode calls f(data).
+func (f DecoderFunc) Decode(data []byte) (any, error) {
+	return f(data)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[DataFormat]Decoder)
+)
+
+func init() {
+	RegisterFormat(Format2, DecoderFunc(func(data []byte) (any, error) { return DecodeFormat2(data) }))
+	RegisterFormat(Format3, DecoderFunc(func(data []byte) (any, error) { return DecodeFormat3(data) }))
+	RegisterFormat(Format4, DecoderFunc(func(data []byte) (any, error) { return DecodeFormat4(data) }))
+	RegisterFormat(Format5, DecoderFunc(func(data []byte) (any, error) { return DecodeFormat5(data) }))
+	RegisterFormat(Format6, DecoderFunc(func(data []byte) (any, error) { return DecodeFormat6(data) }))
+	RegisterFormat(Format8, DecoderFunc(func(data []byte) (any, error) {
+		return DecodeFormat8WithKeyStore(data, DefaultKeyStore)
+	}))
+	RegisterFormat(FormatC5, DecoderFunc(func(data []byte) (any, error) { return DecodeFormatC5(data) }))
+	RegisterFormat(FormatE1, DecoderFunc(func(data []byte) (any, error) { return DecodeFormatE1(data) }))
+}
+
+// RegisterFormat makes a decoder available to DetectFormat and Decode for the
+// given format byte. The built-in formats are registered the same way.
+//
+// RegisterFormat panics if d is nil or a decoder is already registered for
+// format; call UnregisterFormat first to replace one. It is safe for
+// concurrent use, but is typically called from an init function.
+func RegisterFormat(format DataFormat, d Decoder) {
+	if d == nil {
+		panic("tag: RegisterFormat decoder is nil")
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[format]; dup {
+		panic(fmt.Sprintf("tag: RegisterFormat called twice for format %s", format))
+	}
+	registry[format] = d
+}
+
+// UnregisterFormat removes the decoder registered for the given format byte, if any.
+func UnregisterFormat(format DataFormat) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	delete(registry, format)
+}
+
+// RegisteredFormats returns the format bytes that have a registered decoder,
+// in ascending order.
+func RegisteredFormats() []DataFormat {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	formats := make([]DataFormat, 0, len(registry))
+	for format := range registry {
+		formats = append(formats, format)
+	}
+	slices.Sort(formats)
+	return formats
+}
+
+// lookupDecoder returns the decoder registered for format.
+func lookupDecoder(format DataFormat) (Decoder, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	d, ok := registry[format]
+	return d, ok
+}
@@ -0,0 +1,120 @@
+package tag
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// privateData is the result of the test decoder for a private format.
+type privateData struct {
+	Temperature float64
+}
+
+func (p *privateData) Measurement() *Measurement {
+	return &Measurement{Format: 0xF0, Temperature: &p.Temperature}
+}
+
+func registerTestFormat(t *testing.T, format DataFormat, d Decoder) {
+	t.Helper()
+	RegisterFormat(format, d)
+	t.Cleanup(func() { UnregisterFormat(format) })
+}
+
+func TestRegisterFormat_Custom(t *testing.T) {
+	registerTestFormat(t, 0xF0, DecoderFunc(func(data []byte) (any, error) {
+		if len(data) != 2 {
+			return nil, &LengthError{Format: 0xF0, Want: 2, Got: len(data)}
+		}
+		return &privateData{Temperature: float64(int8(data[1]))}, nil
+	}))
+
+	format, err := DetectFormat([]byte{0xF0, 0x15})
+	if err != nil || format != 0xF0 {
+		t.Fatalf("DetectFormat() = %s, %v; want F0", format, err)
+	}
+
+	decoded, err := Decode([]byte{0xF0, 0x15})
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	custom, ok := decoded.Custom.(*privateData)
+	if !ok {
+		t.Fatalf("Custom = %T, want *privateData", decoded.Custom)
+	}
+	if custom.Temperature != 21 {
+		t.Errorf("Temperature = %v, want 21", custom.Temperature)
+	}
+
+	m := decoded.Measurement()
+	if m == nil || m.Temperature == nil || *m.Temperature != 21 {
+		t.Errorf("Measurement() = %+v, want temperature 21", m)
+	}
+
+	if _, err := Decode([]byte{0xF0}); !errors.Is(err, ErrInvalidLength) {
+		t.Errorf("Decode() error = %v, want ErrInvalidLength", err)
+	}
+
+	if !slices.Contains(RegisteredFormats(), 0xF0) {
+		t.Errorf("RegisteredFormats() = %v, missing F0", RegisteredFormats())
+	}
+}
+
+func TestRegisterFormat_BuiltInType(t *testing.T) {
+	// Firmware reusing the RAWv2 layout under a private format byte
+	registerTestFormat(t, 0xF5, DecoderFunc(func(data []byte) (any, error) {
+		raw := append([]byte{0x05}, data[1:]...)
+		return DecodeFormat5(raw)
+	}))
+
+	data := mustDecodeHex(t, "F512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F")
+	decoded, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if decoded.Format != 0xF5 || decoded.Format5 == nil || decoded.Custom != nil {
+		t.Fatalf("Decode() = %+v, want Format5 field populated for format F5", decoded)
+	}
+	if m := decoded.Measurement(); m == nil || m.Format != 0xF5 {
+		t.Errorf("Measurement() = %+v, want format F5", m)
+	}
+}
+
+func TestRegisterFormat_Panics(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  DataFormat
+		decoder Decoder
+	}{
+		{name: "duplicate", format: Format5, decoder: DecoderFunc(func([]byte) (any, error) { return nil, nil })},
+		{name: "nil decoder", format: 0xF1, decoder: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("RegisterFormat() did not panic")
+				}
+			}()
+			RegisterFormat(tt.format, tt.decoder)
+		})
+	}
+}
+
+func TestRegisteredFormats_BuiltIn(t *testing.T) {
+	want := []DataFormat{Format2, Format3, Format4, Format5, Format6, Format8, FormatC5, FormatE1}
+	if got := RegisteredFormats(); !slices.Equal(got, want) {
+		t.Errorf("RegisteredFormats() = %v, want %v", got, want)
+	}
+}
+
+func TestUnregisterFormat(t *testing.T) {
+	d, _ := lookupDecoder(Format3)
+	UnregisterFormat(Format3)
+	t.Cleanup(func() { RegisterFormat(Format3, d) })
+
+	if _, err := Decode(mustDecodeHex(t, "03291A1ECE1EFC18F94202CA0B53")); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Decode() error = %v, want ErrUnknownFormat", err)
+	}
+}